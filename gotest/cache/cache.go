@@ -0,0 +1,225 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"exchangeapp/global"
+	"fmt"
+	"log"
+	"math/rand"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis"
+	"golang.org/x/sync/singleflight"
+)
+
+// ErrNotFound 数据源中不存在该值；Loader 返回该错误时结果会被负缓存
+var ErrNotFound = errors.New("cache: not found")
+
+// Options 单类缓存的参数
+type Options struct {
+	TTL         time.Duration // 值的新鲜期
+	Stale       time.Duration // 过期后仍可返回旧值的时长，期间在后台刷新；为 0 表示不返回旧值
+	NegativeTTL time.Duration // 不存在的结果的缓存时长；为 0 表示不做负缓存
+	Jitter      float64       // 有效期的随机浮动比例，如 0.1 表示 ±10%，避免大量键同时过期
+	Index       string        // 登记缓存键的索引集合，用于 InvalidateIndex 按组失效；为空表示不登记
+}
+
+// entry 写入 Redis 的内容：值本身、新鲜期截止时间和是否为负缓存
+type entry struct {
+	Value    json.RawMessage `json:"v,omitempty"`
+	FreshTil int64           `json:"f"`
+	Missing  bool            `json:"m,omitempty"`
+}
+
+// refreshLockTTL 后台刷新的分布式锁有效期，避免多个实例同时刷新同一个键
+const refreshLockTTL = 10 * time.Second
+
+// downFor Redis 出错后直接读数据源的时长，期间不再访问 Redis，避免每个请求都等待超时
+const downFor = 5 * time.Second
+
+var (
+	group     singleflight.Group
+	downUntil atomic.Int64 // Redis 恢复尝试的时间（Unix 纳秒）
+)
+
+// available 判断当前是否应访问 Redis
+func available() bool {
+	return time.Now().UnixNano() >= downUntil.Load()
+}
+
+// markDown 记录 Redis 故障，在 downFor 内降级为直接读数据源
+func markDown(err error) {
+	if available() {
+		log.Printf("Redis 不可用，缓存降级为直接读取数据源: %v", err)
+	}
+	downUntil.Store(time.Now().Add(downFor).UnixNano())
+}
+
+// Get 按 cache-aside 模式读取 key：命中新鲜值直接返回；命中旧值时返回旧值并在后台刷新；
+// 未命中时调用 load，同一进程内对同一个键的并发加载只执行一次，每个调用方得到各自的副本，可以放心修改。
+// Redis 故障时直接调用 load，写缓存失败不影响返回结果。
+func Get[T any](key string, opts Options, load func() (T, error)) (T, error) {
+	var zero T
+
+	if !available() {
+		return loadShared(key, load)
+	}
+
+	data, err := global.RedisDB.Get(key).Bytes()
+	if err != nil && err != redis.Nil {
+		markDown(err)
+		return loadShared(key, load)
+	}
+
+	if err == nil {
+		var e entry
+		if jsonErr := json.Unmarshal(data, &e); jsonErr == nil {
+			if e.Missing {
+				return zero, ErrNotFound
+			}
+			var value T
+			if jsonErr := json.Unmarshal(e.Value, &value); jsonErr == nil {
+				if time.Now().UnixMilli() >= e.FreshTil {
+					refreshInBackground(key, opts, load)
+				}
+				return value, nil
+			}
+		}
+		// 无法解析的旧格式数据按未命中处理，随后被覆盖
+	}
+
+	v, err, shared := group.Do(key, func() (interface{}, error) {
+		return loadAndStore(key, opts, load)
+	})
+	if err != nil {
+		return zero, err
+	}
+	return own[T](v, shared)
+}
+
+// loadShared 在不访问 Redis 的情况下合并并发加载
+func loadShared[T any](key string, load func() (T, error)) (T, error) {
+	v, err, shared := group.Do(key, func() (interface{}, error) {
+		return load()
+	})
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return own[T](v, shared)
+}
+
+// own 返回调用方独占的值。singleflight 把同一个值交给所有并发的调用方，其中的切片和 map
+// 会被共享，调用方修改时会互相影响，因此共享时按 JSON 复制一份，与从 Redis 读到的值一致
+func own[T any](v interface{}, shared bool) (T, error) {
+	value := v.(T)
+	if !shared {
+		return value, nil
+	}
+
+	var copied T
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return copied, err
+	}
+	if err := json.Unmarshal(raw, &copied); err != nil {
+		return copied, err
+	}
+	return copied, nil
+}
+
+// loadAndStore 调用 load 并写入缓存；写缓存失败只记录日志
+func loadAndStore[T any](key string, opts Options, load func() (T, error)) (T, error) {
+	value, err := load()
+	if errors.Is(err, ErrNotFound) {
+		if opts.NegativeTTL > 0 {
+			store(key, opts, entry{Missing: true}, jitter(opts.NegativeTTL, opts.Jitter))
+		}
+		return value, err
+	} else if err != nil {
+		return value, err
+	}
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return value, err
+	}
+	ttl := jitter(opts.TTL, opts.Jitter)
+	store(key, opts, entry{Value: raw, FreshTil: time.Now().Add(ttl).UnixMilli()}, ttl+opts.Stale)
+	return value, nil
+}
+
+// refreshInBackground 在后台重新加载旧值；跨实例通过 SETNX 锁保证同一时间只有一个实例刷新
+func refreshInBackground[T any](key string, opts Options, load func() (T, error)) {
+	go func() {
+		ok, err := global.RedisDB.SetNX(key+":refresh", 1, refreshLockTTL).Result()
+		if err != nil || !ok {
+			return
+		}
+		defer global.RedisDB.Del(key + ":refresh")
+
+		if _, err, _ := group.Do(key, func() (interface{}, error) {
+			return loadAndStore(key, opts, load)
+		}); err != nil && !errors.Is(err, ErrNotFound) {
+			log.Printf("缓存后台刷新失败 %s: %v", key, err)
+		}
+	}()
+}
+
+// store 写入缓存并登记到索引集合
+func store(key string, opts Options, e entry, ttl time.Duration) {
+	if !available() {
+		return
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+
+	pipe := global.RedisDB.TxPipeline()
+	pipe.Set(key, data, ttl)
+	if opts.Index != "" {
+		pipe.SAdd(opts.Index, key)
+		// 索引集合比其中任何键都活得久，才能保证失效时找到全部键
+		pipe.Expire(opts.Index, opts.TTL+opts.TTL/2+opts.Stale+opts.NegativeTTL)
+	}
+	if _, err := pipe.Exec(); err != nil {
+		markDown(err)
+	}
+}
+
+// jitter 在 d 的基础上随机浮动 ±ratio
+func jitter(d time.Duration, ratio float64) time.Duration {
+	if ratio <= 0 || d <= 0 {
+		return d
+	}
+	return d + time.Duration((rand.Float64()*2-1)*ratio*float64(d))
+}
+
+// Delete 删除缓存键
+func Delete(keys ...string) error {
+	return global.RedisDB.Del(keys...).Err()
+}
+
+// InvalidateIndex 删除索引集合中登记的所有缓存键。
+// 先把索引集合原子地改名，避免与并发写入的登记互相覆盖。
+func InvalidateIndex(index string) error {
+	pending := fmt.Sprintf("%s:%d", index, time.Now().UnixNano())
+	if err := global.RedisDB.Rename(index, pending).Err(); err != nil {
+		// 索引不存在说明当前没有任何缓存
+		if strings.Contains(err.Error(), "no such key") {
+			return nil
+		}
+		return err
+	}
+
+	keys, err := global.RedisDB.SMembers(pending).Result()
+	if err != nil && err != redis.Nil {
+		return err
+	}
+
+	return global.RedisDB.Del(append(keys, pending)...).Err()
+}
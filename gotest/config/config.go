@@ -2,7 +2,8 @@ package config
 
 import (
 	"fmt"
-	"log" // 导入日志包，用于输出错误日志
+	"log"  // 导入日志包，用于输出错误日志
+	"time" // 导入 time 包，用于时长类型的配置项
 
 	"github.com/spf13/viper" // 导入 Viper 库，Viper 是一个配置管理工具
 )
@@ -12,6 +13,7 @@ type Config struct {
 	App struct {
 		Name string // 应用的名称
 		Port string // 应用的端口
+		URL  string // 站点的公开地址，用于生成订阅中的链接；为空时使用请求的地址
 	}
 	Database struct {
 		Dsn          string // 数据库的连接字符串
@@ -23,6 +25,62 @@ type Config struct {
 		DB       int    // Redis 数据库索引
 		Password string // Redis 密码
 	}
+	Rates struct {
+		Consensus struct {
+			Enabled    bool          // 是否以多来源共识（中位数）作为发布汇率
+			MaxAge     time.Duration // 汇率的有效期，超过该时长视为过期，不参与共识
+			MinSources int           // 发布一个货币对所需的最少来源数
+		}
+		Providers []string // 允许的数据提供方名称，只有管理员可以以提供方的名义录入汇率
+	}
+	Search struct {
+		Engine string // 全文检索实现：mysql（FULLTEXT ngram）或 memory（进程内倒排索引）
+	}
+	Sensitive struct {
+		DefaultPolicy string        // 新增敏感词未指定策略时使用的策略：mask、review 或 reject
+		SyncInterval  time.Duration // 检查其他实例是否更新了词库的间隔
+	}
+	Reports struct {
+		HideThreshold int // 被多少名不同用户举报后自动隐藏文章或评论，为 0 表示不自动隐藏
+	}
+	Feed struct {
+		FanoutThreshold int // 粉丝数超过该值的账号不再推送动态到粉丝的动态流，改为粉丝读取时拉取
+		MaxLength       int // 每个动态流和账号动态列表保留的最大条数
+	}
+	JWT struct {
+		Algorithm  string        // 默认的签名算法：HS256、RS256 或 EdDSA，密钥未指定算法时使用
+		Issuer     string        // 签发者（iss），不为空时验证令牌的签发者
+		Audience   string        // 受众（aud），不为空时验证令牌的受众
+		AccessTTL  time.Duration // 访问令牌的有效期
+		RefreshTTL time.Duration // 刷新令牌的有效期
+		ActiveKey  string        // 签发新令牌使用的密钥的 kid
+		Keys       []struct {
+			ID             string // 密钥ID，写入令牌头部的 kid。轮换时新增密钥并切换 ActiveKey，旧密钥保留到其令牌全部过期
+			Algorithm      string // 签名算法，为空时使用默认算法
+			Secret         string // HS256 的密钥
+			PrivateKeyFile string // RS256、EdDSA 的私钥（PEM）文件，签发令牌的密钥必须提供
+			PublicKeyFile  string // RS256、EdDSA 的公钥（PEM）文件，只用于验证的旧密钥提供公钥即可
+		}
+	}
+	Storage struct {
+		Driver    string        // 文件存储实现：local（本地文件系统）或 s3（S3 兼容的对象存储）
+		Secret    string        // 本地存储签名下载地址使用的密钥
+		URLExpiry time.Duration // 签名下载地址的有效期
+		MaxSize   int64         // 上传文件的最大字节数
+		MaxWidth  int           // 图片的最大宽度（像素）
+		MaxHeight int           // 图片的最大高度（像素）
+		Local     struct {
+			Dir string // 本地存储的根目录
+		}
+		S3 struct {
+			Endpoint        string // 服务地址，如 https://s3.amazonaws.com 或本地 MinIO 的 http://127.0.0.1:9000
+			Region          string // 区域，用于请求签名
+			Bucket          string // 存储桶
+			AccessKeyID     string // 访问密钥ID
+			SecretAccessKey string // 访问密钥
+			UsePathStyle    bool   // 使用路径形式（endpoint/bucket/key）访问，MinIO 等本地服务需要开启
+		}
+	}
 }
 
 // AppConfig 是一个全局配置实例，保存从配置文件中读取的配置信息
@@ -1,46 +1,71 @@
 package TeamManagement
 
 import (
+	"errors"
+	"exchangeapp/cache"
 	"exchangeapp/global"
+	"exchangeapp/middlewares"
 	"exchangeapp/models/team"
 	"exchangeapp/models/user"
 	"exchangeapp/rsp"
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
+	"log"
 	"net/http"
+	"strconv"
 )
 
-// CreateTeam 新建团队
+// roleRank 团队角色的级别，成员只能授予不高于自己的角色
+var roleRank = map[string]int{team.RoleMember: 1, team.RoleAdmin: 2, team.RoleOwner: 3}
+
+// CreateTeam 新建团队，当前用户成为团队的拥有者
 // @Summary 新建团队
 // @Description 创建一个新的团队
 // @Tags 团队操作
 // @Param name body string true "团队名称"
 // @Param description body string false "团队描述"
-// @Param owner_id body int true "团队创建者的用户ID"
 // @Success 201 {object} string "注册成功，返回 JWT token"
 // @Failure 400 {object} rsp.ErrorResponse
 // @Router /teams [post]
 func CreateTeam(ctx *gin.Context) {
-	var team team.Team
+	u, err := middlewares.CurrentUser(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, rsp.NewErrorResponse(10002, err.Error(), nil))
+		return
+	}
+
+	var input struct {
+		Name        string `json:"name" binding:"required"`
+		Description string `json:"description"`
+	}
 
 	// 绑定请求体
-	if err := ctx.ShouldBindJSON(&team); err != nil {
-		ctx.JSON(http.StatusBadRequest, rsp.NewErrorResponse(30001, err.Error(), team))
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, rsp.NewErrorResponse(30001, err.Error(), input))
 		return
 	}
 
+	// 拥有者总是当前用户，不接受请求体中的 owner_id
+	team := team.Team{Name: input.Name, Description: input.Description, OwnerID: u.ID}
+
 	// 创建团队
 	if err := global.Db.Create(&team).Error; err != nil {
 		ctx.JSON(http.StatusInternalServerError, rsp.NewErrorResponse(20003, err.Error(), team))
 		return
 	}
 
+	// 清除该 ID 此前可能留下的“不存在”缓存
+	if err := cache.Delete(teamCacheKey(team.ID)); err != nil {
+		log.Printf("清除团队缓存失败: %v", err)
+	}
+	invalidateMemberRoles(team.OwnerID)
+
 	ctx.JSON(http.StatusCreated, rsp.NewSuccessResponse(11001, team))
 }
 
 // AddUserToTeam 将用户添加到团队并设置权限
 // @Summary 添加用户到团队
-// @Description 将指定用户添加到团队中，并为其设置角色和权限
+// @Description 将指定用户添加到团队中，并为其设置角色和权限。只有团队的拥有者和管理员（以及站点管理员）可以添加成员，
+// @Description 且只能授予不高于自己的角色
 // @Tags 团队操作
 // @Param team_id body int true "团队ID"
 // @Param user_id body int true "用户ID"
@@ -50,34 +75,83 @@ func CreateTeam(ctx *gin.Context) {
 // @Failure 400 {object} rsp.ErrorResponse
 // @Router /teams/add_user [post]
 func AddUserToTeam(ctx *gin.Context) {
-	var member team.TeamMember
+	u, err := middlewares.CurrentUser(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, rsp.NewErrorResponse(10002, err.Error(), nil))
+		return
+	}
+
+	var input team.TeamMember
 
 	// 绑定请求体
-	if err := ctx.ShouldBindJSON(&member); err != nil {
-		ctx.JSON(http.StatusBadRequest, rsp.NewErrorResponse(30001, err.Error(), member))
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, rsp.NewErrorResponse(30001, err.Error(), input))
+		return
+	}
+
+	// 只取请求中的团队、用户、角色和权限，忽略 ID 和关联对象
+	member := team.TeamMember{TeamID: input.TeamID, UserID: input.UserID, Role: input.Role, Permissions: input.Permissions}
+	if member.Role == "" {
+		member.Role = team.RoleMember
+	}
+	if !team.ValidRole(member.Role) {
+		ctx.JSON(http.StatusBadRequest, rsp.NewErrorResponse(12003, member.Role, member))
 		return
 	}
 
 	// 检查团队是否存在
-	var t team.Team
-	if err := global.Db.First(&t, member.TeamID).Error; err != nil {
+	if _, err := findTeam(member.TeamID); errors.Is(err, cache.ErrNotFound) {
 		ctx.JSON(http.StatusNotFound, rsp.NewErrorResponse(11002, err.Error(), member.TeamID))
 		return
+	} else if err != nil {
+		ctx.JSON(http.StatusInternalServerError, rsp.NewErrorResponse(20002, err.Error(), member.TeamID))
+		return
+	}
+
+	// 团队的拥有者和管理员可以添加成员，且不能授予高于自己的角色；站点管理员不受限制
+	if !u.IsAdmin() {
+		roles, err := MemberRoles(u.ID)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, rsp.NewErrorResponse(20002, err.Error(), member.TeamID))
+			return
+		}
+		callerRank := roleRank[roles[member.TeamID]]
+		if callerRank < roleRank[team.RoleAdmin] || roleRank[member.Role] > callerRank {
+			ctx.JSON(http.StatusForbidden, rsp.NewErrorResponse(11003, "insufficient team role", member.TeamID))
+			return
+		}
 	}
 
 	// 检查用户是否存在
-	var u user.User
-	if err := global.Db.First(&u, member.UserID).Error; err != nil {
+	if err := global.Db.First(&user.User{}, member.UserID).Error; err != nil {
 		ctx.JSON(http.StatusNotFound, rsp.NewErrorResponse(10002, err.Error(), member.UserID))
 		return
 	}
 
+	// 已在团队中的用户不能重复添加
+	var count int64
+	if err := global.Db.Model(&team.TeamMember{}).Where("team_id = ? AND user_id = ?", member.TeamID, member.UserID).
+		Count(&count).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, rsp.NewErrorResponse(20002, err.Error(), member))
+		return
+	}
+	if count > 0 {
+		ctx.JSON(http.StatusConflict, rsp.NewErrorResponse(12001, "user is already a member", member))
+		return
+	}
+
 	// 添加用户到团队
 	if err := global.Db.Create(&member).Error; err != nil {
 		ctx.JSON(http.StatusInternalServerError, rsp.NewErrorResponse(20003, err.Error(), member))
 		return
 	}
 
+	// 成员列表缓存失效失败时等待其自然过期
+	if err := cache.Delete(teamMembersCacheKey(member.TeamID)); err != nil {
+		log.Printf("清除团队成员缓存失败: %v", err)
+	}
+	invalidateMemberRoles(member.UserID)
+
 	ctx.JSON(http.StatusOK, rsp.NewSuccessResponse(12001, member))
 }
 
@@ -90,15 +164,26 @@ func AddUserToTeam(ctx *gin.Context) {
 // @Failure 404 {object} rsp.ErrorResponse
 // @Router /teams/{id}/members [get]
 func GetTeamMembers(ctx *gin.Context) {
-	teamID := ctx.Param("id")
+	teamID, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, rsp.NewErrorResponse(30002, err.Error(), ctx.Param("id")))
+		return
+	}
 
-	// 查询团队成员信息并预加载用户
-	var members []team.TeamMember
-	if err := global.Db.Preload("User", func(db *gorm.DB) *gorm.DB {
-		return db.Select("id", "username", "email") // 只选择需要的字段
-	}).Where("team_id = ?", teamID).Find(&members).Error; err != nil {
+	// 团队不存在时返回 404，而不是空的成员列表
+	if _, err := findTeam(uint(teamID)); errors.Is(err, cache.ErrNotFound) {
 		ctx.JSON(http.StatusNotFound, rsp.NewErrorResponse(11002, err.Error(), teamID))
 		return
+	} else if err != nil {
+		ctx.JSON(http.StatusInternalServerError, rsp.NewErrorResponse(20002, err.Error(), teamID))
+		return
+	}
+
+	// 查询团队成员信息并预加载用户
+	members, err := findTeamMembers(uint(teamID))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, rsp.NewErrorResponse(20002, err.Error(), teamID))
+		return
 	}
 
 	ctx.JSON(http.StatusOK, rsp.NewSuccessResponse(11002, members))
@@ -0,0 +1,90 @@
+package TeamManagement
+
+import (
+	"errors"
+	"exchangeapp/cache"
+	"exchangeapp/global"
+	"exchangeapp/models/team"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// teamCacheOptions 团队信息缓存的参数。不存在的团队也短暂缓存，防止用不存在的 ID 反复穿透到数据库
+var teamCacheOptions = cache.Options{
+	TTL:         5 * time.Minute,
+	Stale:       time.Minute,
+	NegativeTTL: 30 * time.Second,
+	Jitter:      0.1,
+}
+
+func teamCacheKey(teamID uint) string {
+	return fmt.Sprintf("team:%d", teamID)
+}
+
+func teamMembersCacheKey(teamID uint) string {
+	return fmt.Sprintf("team:%d:members", teamID)
+}
+
+func memberRolesCacheKey(userID uint) string {
+	return fmt.Sprintf("user:%d:team-roles", userID)
+}
+
+// findTeam 读取团队信息，团队不存在时返回 cache.ErrNotFound
+func findTeam(teamID uint) (team.Team, error) {
+	return cache.Get(teamCacheKey(teamID), teamCacheOptions, func() (team.Team, error) {
+		var t team.Team
+		err := global.Db.First(&t, teamID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return t, cache.ErrNotFound
+		}
+		return t, err
+	})
+}
+
+// findTeamMembers 读取团队的成员列表，成员只包含用户的公开字段
+func findTeamMembers(teamID uint) ([]team.TeamMember, error) {
+	return cache.Get(teamMembersCacheKey(teamID), teamCacheOptions, func() ([]team.TeamMember, error) {
+		var members []team.TeamMember
+		err := global.Db.Preload("User", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id", "username", "email") // 只选择需要的字段
+		}).Where("team_id = ?", teamID).Find(&members).Error
+		return members, err
+	})
+}
+
+// MemberRoles 返回用户所在的团队及其在各团队中的角色，团队的创建者视为 owner
+func MemberRoles(userID uint) (map[uint]string, error) {
+	return cache.Get(memberRolesCacheKey(userID), teamCacheOptions, func() (map[uint]string, error) {
+		var members []team.TeamMember
+		if err := global.Db.Select("team_id", "role").Where("user_id = ?", userID).Find(&members).Error; err != nil {
+			return nil, err
+		}
+		var owned []uint
+		if err := global.Db.Model(&team.Team{}).Where("owner_id = ?", userID).Pluck("id", &owned).Error; err != nil {
+			return nil, err
+		}
+
+		roles := make(map[uint]string, len(members)+len(owned))
+		for _, m := range members {
+			role := m.Role
+			if role == "" {
+				role = team.RoleMember
+			}
+			roles[m.TeamID] = role
+		}
+		for _, id := range owned {
+			roles[id] = team.RoleOwner
+		}
+		return roles, nil
+	})
+}
+
+// invalidateMemberRoles 删除用户的团队角色缓存，失败时等待其自然过期
+func invalidateMemberRoles(userID uint) {
+	if err := cache.Delete(memberRolesCacheKey(userID)); err != nil {
+		log.Printf("清除团队角色缓存失败: %v", err)
+	}
+}
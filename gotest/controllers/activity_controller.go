@@ -0,0 +1,381 @@
+package controllers
+
+import (
+	"encoding/json"
+	"exchangeapp/config"
+	"exchangeapp/global"
+	"exchangeapp/middlewares"
+	"exchangeapp/models/artice"
+	"exchangeapp/models/team"
+	"exchangeapp/models/user"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis"
+	"gorm.io/gorm"
+)
+
+// 动态类型
+const (
+	activityArticle = "article" // 发布文章
+	activityComment = "comment" // 发表评论
+	activityLike    = "like"    // 点赞文章
+)
+
+// likeActivityTTL 同一用户对同一文章的点赞动态在该时间内只记录一次，反复取消再点赞不会刷屏
+const likeActivityTTL = 30 * 24 * time.Hour
+
+// activitySeqKey 动态的全局序号，动态流按序号从新到旧排列，也用作分页游标
+const activitySeqKey = "activity:seq"
+
+// 动态流参数的默认值
+const (
+	defaultFanoutThreshold = 1000
+	defaultFeedMaxLength   = 500
+)
+
+// activity 一条动态。只记录公开文章上的动态，读取时再按文章和评论的当前状态过滤
+type activity struct {
+	ID        int64     `json:"id"`
+	Type      string    `json:"type"`
+	ActorID   uint      `json:"actorId"`          // 产生动态的用户
+	TeamID    *uint     `json:"teamId,omitempty"` // 团队文章所属的团队，关注团队的用户同样会收到
+	ArticleID uint      `json:"articleId"`
+	CommentID uint      `json:"commentId,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// feedKey 用户的动态流，保存推送给该用户的动态
+func feedKey(userID uint) string {
+	return fmt.Sprintf("feed:%d", userID)
+}
+
+// outboxKey 用户或团队自己产生的动态，热门账号的粉丝读取时从这里拉取
+func outboxKey(targetType string, targetID uint) string {
+	return fmt.Sprintf("activity:%s:%d", targetType, targetID)
+}
+
+func fanoutThreshold() int {
+	if n := config.AppConfig.Feed.FanoutThreshold; n > 0 {
+		return n
+	}
+	return defaultFanoutThreshold
+}
+
+func feedMaxLength() int64 {
+	if n := config.AppConfig.Feed.MaxLength; n > 0 {
+		return int64(n)
+	}
+	return defaultFeedMaxLength
+}
+
+// recordActivity 记录公开文章上的一条动态：写入产生者（用户以及团队文章所属的团队）自己的动态列表，
+// 并推送到粉丝的动态流。粉丝数超过阈值的账号不推送，由粉丝读取时从其动态列表拉取。
+func recordActivity(a activity, article artice.Article) error {
+	if !article.IsPublic() {
+		return nil
+	}
+	if a.Type == activityArticle {
+		a.TeamID = article.TeamID
+	}
+
+	id, err := global.RedisDB.Incr(activitySeqKey).Result()
+	if err != nil {
+		return err
+	}
+	a.ID = id
+	a.CreatedAt = time.Now()
+	data, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+
+	targets := []user.Follow{{TargetType: user.FollowTargetUser, TargetID: a.ActorID}}
+	if a.TeamID != nil {
+		targets = append(targets, user.Follow{TargetType: user.FollowTargetTeam, TargetID: *a.TeamID})
+	}
+
+	maxLength := feedMaxLength()
+	pipe := global.RedisDB.Pipeline()
+	pushed := make(map[uint]bool)
+	for _, target := range targets {
+		key := outboxKey(target.TargetType, target.TargetID)
+		pipe.LPush(key, data)
+		pipe.LTrim(key, 0, maxLength-1)
+
+		// 多查一条即可判断是否超过阈值
+		var followers []uint
+		if err := global.Db.Model(&user.Follow{}).
+			Where("target_type = ? AND target_id = ?", target.TargetType, target.TargetID).
+			Limit(fanoutThreshold()+1).Pluck("follower_id", &followers).Error; err != nil {
+			return err
+		}
+		if len(followers) > fanoutThreshold() {
+			continue
+		}
+		for _, followerID := range followers {
+			if followerID == a.ActorID || pushed[followerID] {
+				continue
+			}
+			pushed[followerID] = true
+			pipe.LPush(feedKey(followerID), data)
+			pipe.LTrim(feedKey(followerID), 0, maxLength-1)
+		}
+	}
+	_, err = pipe.Exec()
+	return err
+}
+
+// logActivity 记录动态，失败只记录日志，不影响产生动态的操作
+func logActivity(a activity, article artice.Article) {
+	if err := recordActivity(a, article); err != nil {
+		log.Printf("记录动态失败: %v", err)
+	}
+}
+
+// logLikeActivity 记录点赞动态，同一用户对同一文章在 likeActivityTTL 内只记录一次
+func logLikeActivity(userID uint, article artice.Article) {
+	if !article.IsPublic() {
+		return
+	}
+	key := fmt.Sprintf("activity:like:%d:%d", userID, article.ID)
+	first, err := global.RedisDB.SetNX(key, 1, likeActivityTTL).Result()
+	if err != nil {
+		log.Printf("记录动态失败: %v", err)
+		return
+	}
+	if first {
+		logActivity(activity{Type: activityLike, ActorID: userID, ArticleID: article.ID}, article)
+	}
+}
+
+// announceArticle 记录文章发布的动态
+func announceArticle(article artice.Article) {
+	logActivity(activity{Type: activityArticle, ActorID: article.AuthorID, ArticleID: article.ID}, article)
+}
+
+// feedEntry 动态流中的一项及其展示信息
+type feedEntry struct {
+	activity
+	Actor   string       `json:"actor"`          // 产生动态的用户名
+	Team    string       `json:"team,omitempty"` // 团队名称
+	Article feedArticle  `json:"article"`
+	Comment *feedComment `json:"comment,omitempty"`
+}
+
+type feedArticle struct {
+	ID      uint   `json:"id"`
+	Title   string `json:"title"`
+	Preview string `json:"preview"`
+}
+
+type feedComment struct {
+	ID      uint   `json:"id"`
+	Content string `json:"content"`
+}
+
+// GetActivityFeed 分页获取当前用户关注的用户和团队的动态，按时间从新到旧排列。
+// @Summary 获取关注动态
+// @Description 包括关注的用户发布的文章、发表的评论和点赞，以及关注的团队发布的文章；只包含公开文章上的动态。
+// @Tags 关注
+// @Param limit query int false "每页数量，默认 20，最大 100"
+// @Param cursor query string false "上一页返回的 nextCursor"
+// @Produce json
+// @Router /api/feed [get]
+func GetActivityFeed(ctx *gin.Context) {
+	reader, err := middlewares.CurrentUser(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", strconv.Itoa(defaultArticlePageSize)))
+	if err != nil || limit <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+	limit = min(limit, maxArticlePageSize)
+
+	var before int64
+	if cursor := ctx.Query("cursor"); cursor != "" {
+		before, err = strconv.ParseInt(cursor, 10, 64)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+			return
+		}
+	}
+
+	activities, err := collectFeed(reader.ID, before, limit+1)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	nextCursor := ""
+	if len(activities) > limit {
+		activities = activities[:limit]
+		nextCursor = strconv.FormatInt(activities[limit-1].ID, 10)
+	}
+
+	// 文章或评论已删除、不再公开的动态不展示，但不影响翻页
+	entries, err := describeActivities(activities)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"items": entries, "nextCursor": nextCursor})
+}
+
+// collectFeed 合并用户的动态流和所关注热门账号的动态列表，返回序号小于 before（为 0 时不限）的最新 n 条。
+// 只保留当前仍在关注的用户和团队的动态，取消关注前推送的动态不再展示。
+func collectFeed(readerID uint, before int64, n int) ([]activity, error) {
+	var follows []user.Follow
+	if err := global.Db.Where("follower_id = ?", readerID).Find(&follows).Error; err != nil {
+		return nil, err
+	}
+	if len(follows) == 0 {
+		return nil, nil
+	}
+
+	followed := make(map[string]bool, len(follows))
+	targets := global.Db.Session(&gorm.Session{NewDB: true})
+	for _, f := range follows {
+		followed[outboxKey(f.TargetType, f.TargetID)] = true
+		targets = targets.Or("target_type = ? AND target_id = ?", f.TargetType, f.TargetID)
+	}
+
+	// 粉丝数超过阈值的账号不推送动态，需要读取其动态列表
+	var popular []user.Follow
+	if err := global.Db.Model(&user.Follow{}).Select("target_type", "target_id").Where(targets).
+		Group("target_type, target_id").Having("COUNT(*) > ?", fanoutThreshold()).Scan(&popular).Error; err != nil {
+		return nil, err
+	}
+
+	maxLength := feedMaxLength()
+	pipe := global.RedisDB.Pipeline()
+	lists := []*redis.StringSliceCmd{pipe.LRange(feedKey(readerID), 0, maxLength-1)}
+	for _, p := range popular {
+		lists = append(lists, pipe.LRange(outboxKey(p.TargetType, p.TargetID), 0, maxLength-1))
+	}
+	if _, err := pipe.Exec(); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[int64]bool)
+	var activities []activity
+	for _, list := range lists {
+		for _, item := range list.Val() {
+			var a activity
+			if err := json.Unmarshal([]byte(item), &a); err != nil {
+				continue
+			}
+			if seen[a.ID] || (before > 0 && a.ID >= before) || a.ActorID == readerID {
+				continue
+			}
+			if !followed[outboxKey(user.FollowTargetUser, a.ActorID)] &&
+				(a.TeamID == nil || !followed[outboxKey(user.FollowTargetTeam, *a.TeamID)]) {
+				continue
+			}
+			seen[a.ID] = true
+			activities = append(activities, a)
+		}
+	}
+
+	sort.Slice(activities, func(i, j int) bool { return activities[i].ID > activities[j].ID })
+	if len(activities) > n {
+		activities = activities[:n]
+	}
+	return activities, nil
+}
+
+// describeActivities 批量附上动态涉及的用户、团队、文章和评论，跳过文章或评论已不可见的动态
+func describeActivities(activities []activity) ([]feedEntry, error) {
+	entries := make([]feedEntry, 0, len(activities))
+	if len(activities) == 0 {
+		return entries, nil
+	}
+
+	var userIDs, teamIDs, articleIDs, commentIDs []uint
+	for _, a := range activities {
+		userIDs = append(userIDs, a.ActorID)
+		articleIDs = append(articleIDs, a.ArticleID)
+		if a.TeamID != nil {
+			teamIDs = append(teamIDs, *a.TeamID)
+		}
+		if a.CommentID != 0 {
+			commentIDs = append(commentIDs, a.CommentID)
+		}
+	}
+
+	var articles []artice.Article
+	if err := global.Db.Select("id", "title", "preview").
+		Where("id IN ? AND status = ? AND visibility = ?", articleIDs, artice.StatusPublished, artice.VisibilityPublic).
+		Find(&articles).Error; err != nil {
+		return nil, err
+	}
+	var comments []artice.Comment
+	if len(commentIDs) > 0 {
+		if err := global.Db.Select("id", "content").
+			Where("id IN ? AND deleted = ? AND pending = ? AND hidden = ?", commentIDs, false, false, false).
+			Find(&comments).Error; err != nil {
+			return nil, err
+		}
+	}
+	var users []user.User
+	if err := global.Db.Select("id", "username").Where("id IN ?", userIDs).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	var teams []team.Team
+	if len(teamIDs) > 0 {
+		if err := global.Db.Select("id", "name").Where("id IN ?", teamIDs).Find(&teams).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	articleByID := make(map[uint]artice.Article, len(articles))
+	for _, a := range articles {
+		articleByID[a.ID] = a
+	}
+	commentByID := make(map[uint]artice.Comment, len(comments))
+	for _, c := range comments {
+		commentByID[c.ID] = c
+	}
+	usernames := make(map[uint]string, len(users))
+	for _, u := range users {
+		usernames[u.ID] = u.Username
+	}
+	teamNames := make(map[uint]string, len(teams))
+	for _, t := range teams {
+		teamNames[t.ID] = t.Name
+	}
+
+	for _, a := range activities {
+		article, ok := articleByID[a.ArticleID]
+		if !ok {
+			continue
+		}
+		entry := feedEntry{
+			activity: a,
+			Actor:    usernames[a.ActorID],
+			Article:  feedArticle{ID: article.ID, Title: article.Title, Preview: article.Preview},
+		}
+		if a.TeamID != nil {
+			entry.Team = teamNames[*a.TeamID]
+		}
+		if a.CommentID != 0 {
+			comment, ok := commentByID[a.CommentID]
+			if !ok {
+				continue
+			}
+			entry.Comment = &feedComment{ID: comment.ID, Content: comment.Content}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
@@ -0,0 +1,58 @@
+package controllers
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"exchangeapp/cache"
+	"exchangeapp/models/artice"
+	"log"
+	"strings"
+	"time"
+)
+
+// articleCacheIndex 记录文章列表缓存键的集合，写文章时据此失效对应的列表缓存。
+// 未按标签过滤的列表登记在该集合中，按标签过滤的列表登记在 articleCacheIndex:tag:<name> 中，
+// 这样写一篇文章只需失效它所涉及标签的列表，而不必清空全部缓存。
+const articleCacheIndex = "articles:cache-keys"
+
+// articleCacheTTL 文章列表缓存的有效期
+const articleCacheTTL = 10 * time.Minute
+
+// articleListCacheKey 根据规范化后的查询参数生成缓存键，相同查询形状共享同一个键
+func articleListCacheKey(parts ...string) string {
+	sum := sha1.Sum([]byte(strings.Join(parts, "\x00")))
+	return "articles:list:" + hex.EncodeToString(sum[:])
+}
+
+// articleCacheScope 返回列表缓存键应登记的索引集合，tag 为空表示未按标签过滤
+func articleCacheScope(tag string) string {
+	if tag == "" {
+		return articleCacheIndex
+	}
+	return articleCacheIndex + ":tag:" + tag
+}
+
+// articleCacheOptions 返回文章列表缓存的参数：过期后的一分钟内先返回旧列表并在后台刷新，
+// 有效期随机浮动，避免同一时刻写入的大量列表同时失效
+func articleCacheOptions(tag string) cache.Options {
+	return cache.Options{
+		TTL:    articleCacheTTL,
+		Stale:  time.Minute,
+		Jitter: 0.1,
+		Index:  articleCacheScope(tag),
+	}
+}
+
+// invalidateArticleCache 删除未按标签过滤的列表缓存，以及按 tags 中各标签过滤的列表缓存。
+// 调用时数据库写入已经提交，失败时只记录日志，过期的列表由缓存有效期兜底，与 invalidateRateCache 一致
+func invalidateArticleCache(tags ...artice.Tag) {
+	scopes := []string{articleCacheScope("")}
+	for _, tag := range tags {
+		scopes = append(scopes, articleCacheScope(tag.Name))
+	}
+	for _, scope := range scopes {
+		if err := cache.InvalidateIndex(scope); err != nil {
+			log.Printf("清除文章列表缓存失败 %s: %v", scope, err)
+		}
+	}
+}
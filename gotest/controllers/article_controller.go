@@ -1,20 +1,26 @@
 package controllers
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"exchangeapp/cache"
 	"exchangeapp/global"
+	"exchangeapp/markdown"
+	"exchangeapp/middlewares"
 	"exchangeapp/models/artice"
+	"exchangeapp/models/user"
+	"exchangeapp/search"
+	"log"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/go-redis/redis"
 	"gorm.io/gorm"
 )
 
-var cacheKey = "articles"
-
 func CreateArticle(ctx *gin.Context) {
 	var article artice.Article
 
@@ -23,65 +29,296 @@ func CreateArticle(ctx *gin.Context) {
 		return
 	}
 
+	// 作者取自 JWT 中的当前用户，忽略请求体中的作者信息
+	author, err := middlewares.CurrentUser(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	article.AuthorID = author.ID
+	article.Likes = 0
+
+	if !applyArticleVisibility(ctx, &article) {
+		return
+	}
+
+	// 未指定状态时按 PublishAt 判断：未来时间为定时发布，否则立即发布
+	status := article.Status
+	article.Status = ""
+	if err := applyArticleStatus(&article, status, article.PublishAt); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 命中敏感词时按策略拒绝、掩码或送审
+	review, ok := screenArticle(ctx, &article)
+	if !ok {
+		return
+	}
+
+	if err := renderArticle(&article); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	tagNames, err := normalizeTags(article.Tags)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	if err := global.Db.AutoMigrate(&article); err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	if err := global.Db.Create(&article).Error; err != nil {
+	err = global.Db.Transaction(func(tx *gorm.DB) error {
+		if err := validateCategory(tx, article.CategoryID); err != nil {
+			return err
+		}
+		tags, err := resolveTags(tx, tagNames)
+		if err != nil {
+			return err
+		}
+		article.Tags = tags
+		if err := tx.Create(&article).Error; err != nil {
+			return err
+		}
+		if article.Status == artice.StatusPending {
+			if err := syncModeration(tx, artice.ModerationTargetArticle, article.ID, article.AuthorID, review); err != nil {
+				return err
+			}
+		}
+		return recordRevision(tx, article, author.ID)
+	})
+	if errors.Is(err, errCategoryNotFound) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	if err := global.RedisDB.Del(cacheKey).Err(); err != nil {
+	if err := search.Default.Index(article); err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	invalidateArticleCache(article.Tags...)
+
+	if article.Status == artice.StatusScheduled {
+		wakePublishScheduler()
+	}
+	announceArticle(article)
+
 	ctx.JSON(http.StatusCreated, article)
 }
 
+// 文章列表分页参数
+const (
+	defaultArticlePageSize = 20
+	maxArticlePageSize     = 100
+)
+
+// articlePage 文章列表的一页，同时也是列表缓存的内容
+type articlePage struct {
+	Articles   []artice.Article `json:"articles"`
+	NextCursor string           `json:"nextCursor"`
+}
+
+// articleCursor 游标分页的位置，记录上一页最后一篇文章的排序字段和ID
+type articleCursor struct {
+	CreatedAt time.Time `json:"c,omitempty"`
+	Likes     int64     `json:"l,omitempty"`
+	ID        uint      `json:"i"`
+}
+
+// encodeArticleCursor 将游标编码为 URL 安全的字符串
+func encodeArticleCursor(cursor articleCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeArticleCursor 解析客户端传回的游标
+func decodeArticleCursor(value string) (articleCursor, error) {
+	var cursor articleCursor
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return cursor, errors.New("invalid cursor")
+	}
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return cursor, errors.New("invalid cursor")
+	}
+	return cursor, nil
+}
+
+// GetArticles 分页获取文章列表。
+// @Summary 获取文章列表
+// @Description 基于游标分页，支持按创建时间或点赞数排序，按作者、标签、分类和创建时间范围过滤。
+// @Tags 文章操作
+// @Param limit query int false "每页数量，默认 20，最大 100"
+// @Param cursor query string false "上一页返回的 nextCursor"
+// @Param sort query string false "排序方式：created（默认）或 likes"
+// @Param author query int false "作者ID"
+// @Param tag query string false "标签名"
+// @Param category query int false "分类ID，包含下级分类"
+// @Param from query string false "创建时间起点"
+// @Param to query string false "创建时间终点"
+// @Param status query string false "文章状态，仅在 author 为当前用户时可用；默认只返回已发布的文章"
+// @Produce json
+// @Router /api/articles [get]
 func GetArticles(ctx *gin.Context) {
+	limit := defaultArticlePageSize
+	if value := ctx.Query("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+		limit = min(n, maxArticlePageSize)
+	}
 
-	cachedData, err := global.RedisDB.Get(cacheKey).Result()
+	sortBy := ctx.DefaultQuery("sort", "created")
+	if sortBy != "created" && sortBy != "likes" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid sort " + sortBy})
+		return
+	}
+
+	viewer, err := middlewares.CurrentUser(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
 
-	if err == redis.Nil {
-		var articles []artice.Article
+	query := global.Db.Model(&artice.Article{})
 
-		if err := global.Db.Find(&articles).Error; err != nil {
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
-			} else {
-				ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			}
+	// 作者查看自己的文章列表时可以看到草稿、定时和归档文章，其他列表只包含已发布的文章
+	ownList := false
+	if author := ctx.Query("author"); author != "" {
+		authorID, err := strconv.ParseUint(author, 10, 64)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid author"})
+			return
+		}
+		query = query.Where("author_id = ?", authorID)
+		ownList = uint(authorID) == viewer.ID
+	}
+
+	status := ctx.Query("status")
+	if status != "" && !validArticleStatus(status) && status != artice.StatusPending && status != artice.StatusHidden {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid status " + status})
+		return
+	}
+	if !ownList {
+		if status != "" && status != artice.StatusPublished {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": "only the author can list unpublished articles"})
 			return
 		}
+		status = artice.StatusPublished
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
 
-		articleJSON, err := json.Marshal(articles)
+	// 他人的列表只包含当前用户可见的团队文章，缓存按可见范围区分，避免团队文章出现在非成员的列表中
+	audience := ""
+	if !ownList {
+		scope, err := audienceOf(viewer)
 		if err != nil {
 			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
+		query = query.Scopes(scope.scope)
+		audience = scope.cacheKey()
+	}
+	if from := ctx.Query("from"); from != "" {
+		t, err := parseQueryTime(from)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		query = query.Where("created_at >= ?", t)
+	}
+	if to := ctx.Query("to"); to != "" {
+		t, err := parseQueryTime(to)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		query = query.Where("created_at <= ?", t)
+	}
 
-		if err := global.RedisDB.Set(cacheKey, articleJSON, 10*time.Minute).Err(); err != nil {
+	tag := normalizeTagName(ctx.Query("tag"))
+	if tag != "" {
+		query = query.Where("id IN (?)", global.Db.Table("article_tags").
+			Select("article_tags.article_id").
+			Joins("JOIN tags ON tags.id = article_tags.tag_id").
+			Where("tags.name = ?", tag))
+	}
+	if category := ctx.Query("category"); category != "" {
+		categoryID, err := strconv.ParseUint(category, 10, 64)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid category"})
+			return
+		}
+		// 按分类过滤时包含其所有下级分类
+		categoryIDs, err := categoryWithDescendants(global.Db, uint(categoryID))
+		if err != nil {
 			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
+		query = query.Where("category_id IN ?", categoryIDs)
+	}
 
-		ctx.JSON(http.StatusOK, articles)
+	if value := ctx.Query("cursor"); value != "" {
+		cursor, err := decodeArticleCursor(value)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if sortBy == "likes" {
+			query = query.Where("likes < ? OR (likes = ? AND id < ?)", cursor.Likes, cursor.Likes, cursor.ID)
+		} else {
+			query = query.Where("created_at < ? OR (created_at = ? AND id < ?)", cursor.CreatedAt, cursor.CreatedAt, cursor.ID)
+		}
+	}
 
-	} else if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+	if sortBy == "likes" {
+		query = query.Order("likes desc, id desc")
 	} else {
-		var articles []artice.Article
+		query = query.Order("created_at desc, id desc")
+	}
 
-		if err := json.Unmarshal([]byte(cachedData), &articles); err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
+	// 每种查询形状单独缓存，避免一个大缓存包含全部文章
+	// 作者自己的列表包含未发布文章，与他人看到的同一作者列表分开缓存
+	key := articleListCacheKey(sortBy, strconv.Itoa(limit), ctx.Query("cursor"),
+		ctx.Query("author"), ctx.Query("from"), ctx.Query("to"), tag, ctx.Query("category"),
+		strconv.FormatBool(ownList), status, audience)
+
+	// 按标签过滤的列表登记在该标签的索引下，只在该标签的文章变更时失效
+	page, err := cache.Get(key, articleCacheOptions(tag), func() (articlePage, error) {
+		var page articlePage
+		if err := query.Preload("Tags").Limit(limit).Find(&page.Articles).Error; err != nil {
+			return page, err
 		}
-		ctx.JSON(http.StatusOK, articles)
+		if len(page.Articles) == limit {
+			last := page.Articles[len(page.Articles)-1]
+			page.NextCursor = encodeArticleCursor(articleCursor{CreatedAt: last.CreatedAt, Likes: last.Likes, ID: last.ID})
+		}
+		return page, nil
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
+
+	// 点赞数、当前用户的点赞和收藏状态不进入共享缓存，返回前实时填充
+	if err := annotateArticles(page.Articles, viewer.ID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, page)
 }
 
 func GetArticleByID(ctx *gin.Context) {
@@ -89,7 +326,7 @@ func GetArticleByID(ctx *gin.Context) {
 
 	var article artice.Article
 
-	if err := global.Db.Where("id = ?", id).First(&article).Error; err != nil {
+	if err := global.Db.Preload("Tags").Where("id = ?", id).First(&article).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		} else {
@@ -98,5 +335,381 @@ func GetArticleByID(ctx *gin.Context) {
 		return
 	}
 
+	// 路由使用可选的身份验证，未登录的访客按 ID 为 0 的匿名用户处理
+	viewer, err := middlewares.CurrentUser(ctx)
+	if err != nil && !errors.Is(err, middlewares.ErrNoUser) {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 未发布的文章和团队文章对无权查看的用户如同不存在
+	if visible, err := articleVisibleTo(&article, &viewer); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	} else if !visible {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": gorm.ErrRecordNotFound.Error()})
+		return
+	}
+
+	// 早于 Markdown 渲染上线的文章在首次读取时补充渲染结果
+	if article.ContentHTML == "" && article.Content != "" {
+		if err := renderArticle(&article); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := global.Db.Model(&article).UpdateColumns(map[string]interface{}{
+			"content_html": article.ContentHTML, "preview": article.Preview,
+			"word_count": article.WordCount, "reading_time": article.ReadingTime,
+		}).Error; err != nil {
+			log.Printf("保存文章渲染结果失败: %v", err)
+		}
+	}
+
+	// 统计浏览，每位访客每天的首次浏览计入热度和排行榜；记录失败不影响文章的读取
+	if article.IsPublished() {
+		unique, err := recordView(article.ID, viewerIdentity(ctx, viewer.ID))
+		if err != nil {
+			log.Printf("记录文章浏览失败: %v", err)
+		} else if unique {
+			if err := recordInteraction(article, viewWeight, 1); err != nil {
+				log.Printf("记录文章浏览失败: %v", err)
+			}
+		}
+	}
+
+	// 系列文章附上前后篇，并记录读者的阅读进度
+	nav, err := seriesNav(article, &viewer)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	article.Series = nav
+	if nav != nil && article.IsPublished() && viewer.ID != 0 {
+		recordSeriesRead(viewer.ID, nav.ID, article.ID)
+	}
+
+	articles := []artice.Article{article}
+	if err := annotateArticles(articles, viewer.ID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, articles[0])
+}
+
+// SearchArticles 全文检索文章。
+// @Summary 搜索文章
+// @Description 在标题、正文和预览中检索，按相关度排序，返回带 <em> 高亮的标题和摘要。
+// @Tags 文章操作
+// @Param q query string true "检索词"
+// @Param limit query int false "返回数量，默认 20，最大 100"
+// @Param offset query int false "偏移量"
+// @Produce json
+// @Router /api/articles/search [get]
+func SearchArticles(ctx *gin.Context) {
+	q := strings.TrimSpace(ctx.Query("q"))
+	if q == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "missing query parameter q"})
+		return
+	}
+
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", strconv.Itoa(defaultArticlePageSize)))
+	if err != nil || limit <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+	limit = min(limit, maxArticlePageSize)
+
+	offset, err := strconv.Atoi(ctx.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid offset"})
+		return
+	}
+
+	results, err := search.Default.Search(q, limit, offset)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, results)
+}
+
+// UpdateArticle 更新文章，仅作者本人或管理员可操作。
+// @Summary 更新文章
+// @Tags 文章操作
+// @Param id path string true "文章ID"
+// @Accept json
+// @Produce json
+// @Router /api/articles/{id} [put]
+func UpdateArticle(ctx *gin.Context) {
+	article, editor, ok := findOwnedArticle(ctx)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Title      string `binding:"required"`
+		Content    string `binding:"required"`
+		Preview    string // 为空时由正文自动生成
+		CategoryID *uint
+		Tags       []artice.Tag
+		Status     string     // 为空时保持原状态
+		PublishAt  *time.Time // 为空时保持原发布时间
+		// 为空时保持原所属团队和可见范围，否则三者一起更新
+		Visibility   string
+		TeamID       *uint
+		VisibleRoles artice.RoleSet
+	}
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tagNames, err := normalizeTags(input.Tags)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 旧标签和新标签下的列表缓存都需要失效
+	previous := article
+	affectedTags := article.Tags
+
+	article.Title = input.Title
+	article.Content = input.Content
+	article.Preview = input.Preview
+	article.CategoryID = input.CategoryID
+
+	if input.Visibility != "" {
+		article.Visibility = input.Visibility
+		article.TeamID = input.TeamID
+		article.VisibleRoles = input.VisibleRoles
+		if !applyArticleVisibility(ctx, &article) {
+			return
+		}
+	}
+
+	// 被举报隐藏的文章在举报处理前保持隐藏，作者只能修改内容
+	if article.Status == artice.StatusHidden {
+		if input.Status != "" || input.PublishAt != nil {
+			ctx.JSON(http.StatusConflict, gin.H{"error": "the article is hidden pending review of reports"})
+			return
+		}
+	} else {
+		// 待审核的文章保持原状态时按发布时间重新确定状态，随后重新检查敏感词
+		if input.Status == "" && article.Status != artice.StatusPending {
+			input.Status = article.Status
+		}
+		if input.PublishAt == nil {
+			input.PublishAt = article.PublishAt
+		}
+		if err := applyArticleStatus(&article, input.Status, input.PublishAt); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	review, ok := screenArticle(ctx, &article)
+	if !ok {
+		return
+	}
+	// 只有待审核的文章需要审核项，其余情况撤回之前的待审核项
+	if article.Status != artice.StatusPending {
+		review = nil
+	}
+
+	if err := renderArticle(&article); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	err = global.Db.Transaction(func(tx *gorm.DB) error {
+		if err := ensureBaseRevision(tx, previous); err != nil {
+			return err
+		}
+		if err := validateCategory(tx, article.CategoryID); err != nil {
+			return err
+		}
+		tags, err := resolveTags(tx, tagNames)
+		if err != nil {
+			return err
+		}
+		if err := tx.Omit("Tags").Save(&article).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&article).Association("Tags").Replace(tags); err != nil {
+			return err
+		}
+		article.Tags = tags
+		if err := syncModeration(tx, artice.ModerationTargetArticle, article.ID, article.AuthorID, review); err != nil {
+			return err
+		}
+		return recordRevision(tx, article, editor.ID)
+	})
+	if errors.Is(err, errCategoryNotFound) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := search.Default.Index(article); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	invalidateArticleCache(append(affectedTags, article.Tags...)...)
+
+	if article.Status == artice.StatusScheduled {
+		wakePublishScheduler()
+	}
+	// 草稿等未公开的文章在编辑后公开时才算发布
+	if !previous.IsPublic() {
+		announceArticle(article)
+	}
+
 	ctx.JSON(http.StatusOK, article)
 }
+
+// DeleteArticle 删除文章，仅作者本人或管理员可操作。
+// @Summary 删除文章
+// @Tags 文章操作
+// @Param id path string true "文章ID"
+// @Router /api/articles/{id} [delete]
+func DeleteArticle(ctx *gin.Context) {
+	article, _, ok := findOwnedArticle(ctx)
+	if !ok {
+		return
+	}
+
+	if err := removeArticle(article); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Successfully deleted the article"})
+}
+
+// removeArticle 删除文章，并清理检索索引、列表缓存、计数、排行榜和收藏等派生数据；article 须预加载 Tags
+func removeArticle(article artice.Article) error {
+	if err := global.Db.Delete(&article).Error; err != nil {
+		return err
+	}
+
+	if err := search.Default.Remove(article.ID); err != nil {
+		return err
+	}
+
+	// 同时清理文章列表缓存和该文章的点赞、评论计数及访客统计
+	invalidateArticleCache(article.Tags...)
+	if err := global.RedisDB.Del(likeCountKey(article.ID), likersKey(article.ID), everLikedKey(article.ID),
+		commentCountKey(article.ID), viewersKey(article.ID)).Err(); err != nil {
+		return err
+	}
+
+	if err := removeFromRankings(article.ID); err != nil {
+		return err
+	}
+
+	// 从所有阅读列表中移除；列表查询本身也会跳过已删除的文章，清理失败不影响删除结果
+	if err := global.Db.Where("article_id = ?", article.ID).Delete(&artice.Bookmark{}).Error; err != nil {
+		log.Printf("清理文章收藏失败: %v", err)
+	}
+	if err := syncModeration(global.Db, artice.ModerationTargetArticle, article.ID, article.AuthorID, nil); err != nil {
+		log.Printf("撤回文章的待审核项失败: %v", err)
+	}
+	// 移出所属系列；系列查询本身也会跳过已删除的文章
+	if err := global.Db.Where("article_id = ?", article.ID).Delete(&artice.SeriesArticle{}).Error; err != nil {
+		log.Printf("把文章移出系列失败: %v", err)
+	}
+	return nil
+}
+
+// findOwnedArticle 查询路径参数 id 对应的文章和当前用户，并校验当前用户是作者或管理员；失败时直接写入响应
+func findOwnedArticle(ctx *gin.Context) (artice.Article, user.User, bool) {
+	var article artice.Article
+
+	if err := global.Db.Preload("Tags").Where("id = ?", ctx.Param("id")).First(&article).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return article, user.User{}, false
+	}
+
+	u, err := middlewares.CurrentUser(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return article, u, false
+	}
+
+	if article.AuthorID != u.ID && !u.IsAdmin() {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "only the author or an admin can modify this article"})
+		return article, u, false
+	}
+
+	return article, u, true
+}
+
+// previewLength 自动生成的摘要的最大字符数
+const previewLength = 200
+
+// renderArticle 把 Markdown 正文渲染为安全的 HTML，计算字数和阅读时间，并在未提供摘要时生成纯文本摘要
+func renderArticle(article *artice.Article) error {
+	doc, err := markdown.Render(article.Content)
+	if err != nil {
+		return err
+	}
+
+	article.ContentHTML = doc.HTML
+	article.WordCount = doc.WordCount
+	article.ReadingTime = doc.ReadingTime
+	if strings.TrimSpace(article.Preview) == "" {
+		article.Preview = markdown.Preview(doc.Text, previewLength)
+	}
+	return nil
+}
+
+// validArticleStatus 判断是否为合法的文章状态
+func validArticleStatus(status string) bool {
+	switch status {
+	case artice.StatusDraft, artice.StatusScheduled, artice.StatusPublished, artice.StatusArchived:
+		return true
+	}
+	return false
+}
+
+// applyArticleStatus 校验并设置文章的状态和发布时间。
+// status 为空时，publishAt 在未来则为定时发布，否则立即发布；定时发布必须指定未来的 publishAt；
+// 文章首次发布时发布时间记为当前时间。
+func applyArticleStatus(article *artice.Article, status string, publishAt *time.Time) error {
+	now := time.Now()
+
+	if status == "" {
+		status = artice.StatusPublished
+		if publishAt != nil && publishAt.After(now) {
+			status = artice.StatusScheduled
+		}
+	}
+	if !validArticleStatus(status) {
+		return errors.New("invalid status " + status)
+	}
+
+	switch status {
+	case artice.StatusScheduled:
+		if publishAt == nil || !publishAt.After(now) {
+			return errors.New("scheduled articles need a publishAt in the future")
+		}
+	case artice.StatusPublished:
+		if article.Status != artice.StatusPublished || publishAt == nil || publishAt.After(now) {
+			publishAt = &now
+		}
+	}
+
+	article.Status = status
+	article.PublishAt = publishAt
+	return nil
+}
@@ -0,0 +1,145 @@
+package controllers
+
+import (
+	"exchangeapp/controllers/TeamManagement"
+	"exchangeapp/models/artice"
+	"exchangeapp/models/team"
+	"exchangeapp/models/user"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// articleAudience 用户能看到的团队文章范围：管理员可以看到所有文章，其他用户取决于所在团队及其中的角色
+type articleAudience struct {
+	admin bool
+	roles map[uint]string // 团队ID -> 角色
+}
+
+// audienceOf 查询用户的团队角色
+func audienceOf(u user.User) (articleAudience, error) {
+	if u.IsAdmin() {
+		return articleAudience{admin: true}, nil
+	}
+	roles, err := TeamManagement.MemberRoles(u.ID)
+	if err != nil {
+		return articleAudience{}, err
+	}
+	return articleAudience{roles: roles}, nil
+}
+
+// cacheKey 可见范围的规范化表示。文章列表缓存以此区分不同的可见范围，
+// 团队和角色都相同的用户共享同一份缓存，不在任何团队中的用户共享公开文章的缓存。
+func (a articleAudience) cacheKey() string {
+	if a.admin {
+		return "admin"
+	}
+	parts := make([]string, 0, len(a.roles))
+	for teamID, role := range a.roles {
+		parts = append(parts, fmt.Sprintf("%d:%s", teamID, role))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
+
+// scope 只保留该范围内可见的文章，用于 articles 表的查询
+func (a articleAudience) scope(db *gorm.DB) *gorm.DB {
+	if a.admin {
+		return db
+	}
+
+	cond := db.Session(&gorm.Session{NewDB: true}).Where("articles.visibility = ?", artice.VisibilityPublic)
+	if len(a.roles) > 0 {
+		teamIDs := make([]uint, 0, len(a.roles))
+		byRole := make(map[string][]uint)
+		for teamID, role := range a.roles {
+			teamIDs = append(teamIDs, teamID)
+			byRole[role] = append(byRole[role], teamID)
+		}
+		cond = cond.Or("articles.visibility = ? AND articles.team_id IN ?", artice.VisibilityTeam, teamIDs)
+		for _, role := range []string{team.RoleOwner, team.RoleAdmin, team.RoleMember} {
+			if ids := byRole[role]; len(ids) > 0 {
+				cond = cond.Or("articles.visibility = ? AND articles.team_id IN ? AND FIND_IN_SET(?, articles.visible_roles)",
+					artice.VisibilityRoles, ids, role)
+			}
+		}
+	}
+	return db.Where(cond)
+}
+
+// canView 判断文章对该范围内的用户 u 是否可见
+func (a articleAudience) canView(article *artice.Article, u *user.User) bool {
+	role := ""
+	if article.TeamID != nil {
+		role = a.roles[*article.TeamID]
+	}
+	return article.VisibleTo(u, role)
+}
+
+// articleVisibleTo 判断文章对用户是否可见，团队文章需要查询用户在团队中的角色
+func articleVisibleTo(article *artice.Article, u *user.User) (bool, error) {
+	if article.TeamID == nil || article.Visibility == artice.VisibilityPublic || article.AuthorID == u.ID || u.IsAdmin() {
+		return article.VisibleTo(u, ""), nil
+	}
+	audience, err := audienceOf(*u)
+	if err != nil {
+		return false, err
+	}
+	return audience.canView(article, u), nil
+}
+
+// applyArticleVisibility 校验并规范化文章的所属团队和可见范围：团队文章的作者必须是该团队的成员，
+// 团队可见和按角色可见的文章必须属于某个团队。失败时直接写入响应并返回 false。
+func applyArticleVisibility(ctx *gin.Context, article *artice.Article) bool {
+	switch article.Visibility {
+	case "":
+		article.Visibility = artice.VisibilityPublic
+	case artice.VisibilityPublic, artice.VisibilityTeam, artice.VisibilityRoles:
+	default:
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid visibility " + article.Visibility})
+		return false
+	}
+
+	if article.Visibility == artice.VisibilityRoles {
+		var roles artice.RoleSet
+		for _, role := range article.VisibleRoles {
+			if !team.ValidRole(role) {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid role " + role})
+				return false
+			}
+			if !roles.Contains(role) {
+				roles = append(roles, role)
+			}
+		}
+		if len(roles) == 0 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "visibleRoles is required when visibility is roles"})
+			return false
+		}
+		article.VisibleRoles = roles
+	} else {
+		article.VisibleRoles = nil
+	}
+
+	if article.TeamID == nil {
+		if article.Visibility != artice.VisibilityPublic {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "teamId is required when visibility is " + article.Visibility})
+			return false
+		}
+		return true
+	}
+
+	roles, err := TeamManagement.MemberRoles(article.AuthorID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	if _, ok := roles[*article.TeamID]; !ok {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "the author is not a member of this team"})
+		return false
+	}
+	return true
+}
@@ -4,14 +4,20 @@ import (
 	"errors"
 	"exchangeapp/global" // 引入全局包，用于访问数据库实例
 	"exchangeapp/models/user"
-	"exchangeapp/rsp"   // 引入错误处理包
-	"exchangeapp/utils" // 引入工具包，处理密码加密、JWT 生成等操作
+	"exchangeapp/rsp"       // 引入错误处理包
+	"exchangeapp/sensitive" // 引入敏感词过滤包，检查用户名
+	"exchangeapp/utils"     // 引入工具包，处理密码加密、JWT 生成等操作
 	"fmt"
 	"github.com/gin-gonic/gin" // 引入 Gin 框架，用于处理 Web 请求和响应
 	"github.com/go-redis/redis"
+	"gorm.io/gorm"
 	"net/http" // 引入 HTTP 包，用于定义 HTTP 状态码
+	"strings"
 )
 
+// dummyPasswordHash 账号不存在时用于校验密码的哈希（成本因子同样为 12），使响应时间与密码错误时一致
+const dummyPasswordHash = "$2a$12$8I3DcHaK5ETW4ILcxSW65.XQ69vIl9485mUNdqOWqqQHpLkrV49YS"
+
 // Register 处理用户注册请求
 // @Summary 用户注册接口
 // @Description 用户通过用户名和密码注册
@@ -42,13 +48,19 @@ func Register(ctx *gin.Context) {
 		panic(rsp.NewErrorResponse(30001, err.Error(), req))
 	}
 
-	// 从 req.Account 中提取用户数据
+	// 从 req.Account 中提取用户数据，邮箱统一转为小写
+	email := utils.NormalizeEmail(req.Account.Email)
 	user := user.User{
-		Email:    &req.Account.Email,
+		Email:    &email,
 		Username: req.Account.Username,
 		Password: req.Account.Password,
 	}
 
+	// 用户名命中任何策略的敏感词都拒绝注册，用户名不做掩码或送审
+	if result := sensitive.Current().Check(user.Username); len(result.Masked)+len(result.Review)+len(result.Reject) > 0 {
+		panic(rsp.NewErrorResponse(13003, "用户名包含敏感词", req))
+	}
+
 	// 验证邮箱格式
 	if &user.Email == nil || !utils.IsValidEmail(*user.Email) {
 		// 使用 panic 抛出错误，传入错误信息、请求数据
@@ -64,9 +76,6 @@ func Register(ctx *gin.Context) {
 	// 从 Redis 中获取验证码
 	redisKey := fmt.Sprintf("verification:%s", *user.Email)
 	storedCode, err := global.RedisDB.Get(redisKey).Result()
-
-	fmt.Println(redisKey)
-	fmt.Println(storedCode)
 	if errors.Is(err, redis.Nil) {
 		// Redis 中没有该验证码，说明验证码过期或不存在
 		panic(rsp.NewErrorResponse(40005, "验证码已过期或无效", redis.Nil))
@@ -89,62 +98,108 @@ func Register(ctx *gin.Context) {
 	}
 	user.Password = hashedPwd
 
-	// 生成 JWT
-	token, err := utils.GenerateJWT(user.Username)
-	if err != nil {
-		panic(rsp.NewErrorResponse(50001, err.Error(), token))
-	}
-
 	// 插入用户数据
 	if err := global.Db.Create(&user).Error; err != nil {
 		panic(rsp.NewErrorResponse(20003, err.Error(), user))
 	}
 
-	// 返回生成的 JWT
-	ctx.JSON(http.StatusOK, gin.H{"token": token})
+	// 签发访问令牌和刷新令牌
+	tokens, err := issueSession(user, "")
+	if err != nil {
+		panic(rsp.NewErrorResponse(50001, err.Error(), nil))
+	}
+
+	// 返回生成的令牌
+	ctx.JSON(http.StatusOK, tokens)
+}
+
+// findLoginUser 按登录标识查询用户：邮箱和手机号规范化后查询，其余按用户名查询。
+// 看起来像手机号的标识同时匹配手机号和用户名（兼容纯数字的用户名），手机号优先；
+// 两者合并为一次查询，使响应时间不因手机号是否存在而不同
+func findLoginUser(identifier string) (user.User, error) {
+	var u user.User
+	username := strings.TrimSpace(identifier)
+	kind, value, err := utils.ParseIdentifier(identifier)
+	switch {
+	case kind == utils.IdentifierEmail:
+		if err != nil {
+			return u, gorm.ErrRecordNotFound
+		}
+		return u, global.Db.Where("email = ?", value).First(&u).Error
+	case kind == utils.IdentifierPhone && err == nil:
+		var users []user.User
+		if err := global.Db.Where("phone = ? OR username = ?", value, username).Limit(2).Find(&users).Error; err != nil {
+			return u, err
+		}
+		if len(users) == 0 {
+			return u, gorm.ErrRecordNotFound
+		}
+		for _, candidate := range users {
+			if candidate.Phone != nil && *candidate.Phone == value {
+				return candidate, nil
+			}
+		}
+		return users[0], nil
+	}
+	return u, global.Db.Where("username = ?", username).First(&u).Error
 }
 
 // Login 处理用户登录请求
 // @Summary 用户登录接口
-// @Description 用户通过用户名和密码登录，返回 JWT token
+// @Description 用户通过邮箱、手机号或用户名和密码登录，返回访问令牌和刷新令牌。账号不存在和密码错误返回相同的错误
 // @Tags Auth
 // @Accept json
 // @Produce json
-// @Param username body string true "用户名" // 用户名
+// @Param identifier body string true "邮箱、手机号或用户名" // 旧客户端可以继续使用 username 字段
 // @Param password body string true "密码"   // 用户密码
-// @Success 200 {string} string "登录成功，返回 JWT token"
-// @Failure 400 {string} string "用户名或密码错误"
+// @Success 200 {string} string "登录成功，返回访问令牌和刷新令牌"
+// @Failure 400 {string} string "账号或密码错误"
 // @Failure 401 {string} string "用户未授权"
 // @Router /api/auth/login [post]
 func Login(ctx *gin.Context) {
 	var input struct {
-		Username string `json:"username" binding:"required"` // 必填字段
-		Password string `json:"password" binding:"required"` // 必填字段
+		Identifier string `json:"identifier"`                  // 邮箱、手机号或用户名
+		Username   string `json:"username"`                    // 兼容旧客户端，等同于 identifier
+		Password   string `json:"password" binding:"required"` // 必填字段
 	}
 
-	// 绑定 JSON 数据到 input 结构体
+	// 绑定 JSON 数据到 input 结构体；错误响应中不回显输入，避免带出密码
 	if err := ctx.ShouldBindJSON(&input); err != nil {
-		panic(rsp.NewErrorResponse(30001, err.Error(), input)) // 使用 panic 抛出错误
+		panic(rsp.NewErrorResponse(30001, err.Error(), nil)) // 使用 panic 抛出错误
+	}
+	identifier := input.Identifier
+	if identifier == "" {
+		identifier = input.Username
+	}
+	if strings.TrimSpace(identifier) == "" {
+		panic(rsp.NewErrorResponse(30001, "identifier is required", nil))
 	}
 
-	var user user.User
-
-	// 从数据库中查询用户
-	if err := global.Db.Where("username = ?", input.Username).First(&user).Error; err != nil {
-		panic(rsp.NewErrorResponse(10002, err.Error(), input)) // 使用 panic 抛出错误
+	// 从数据库中查询用户；账号不存在时同样校验一次密码，避免通过错误信息或响应时间探测账号是否存在
+	user, err := findLoginUser(identifier)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.CheckPassword(input.Password, dummyPasswordHash)
+		panic(rsp.NewErrorResponse(10003, nil, nil)) // 使用 panic 抛出错误
+	} else if err != nil {
+		panic(rsp.NewErrorResponse(20002, err.Error(), nil))
 	}
 
 	// 校验密码
 	if !utils.CheckPassword(input.Password, user.Password) {
-		panic(rsp.NewErrorResponse(10003, input.Password, input)) // 使用 panic 抛出错误
+		panic(rsp.NewErrorResponse(10003, nil, nil)) // 使用 panic 抛出错误
+	}
+
+	// 已封禁的用户不能登录；只有密码正确时才会走到这里，不会泄露账号是否存在
+	if user.IsBanned {
+		panic(rsp.NewErrorResponse(10004, nil, nil))
 	}
 
-	// 生成 JWT
-	token, err := utils.GenerateJWT(user.Username)
+	// 签发访问令牌和刷新令牌
+	tokens, err := issueSession(user, "")
 	if err != nil {
-		panic(rsp.NewErrorResponse(50001, err.Error(), input)) // 使用 panic 抛出错误
+		panic(rsp.NewErrorResponse(50001, err.Error(), nil)) // 使用 panic 抛出错误
 	}
 
-	// 返回成功响应，包含生成的 JWT token
-	ctx.JSON(http.StatusOK, gin.H{"token": token})
+	// 返回成功响应，包含访问令牌和刷新令牌
+	ctx.JSON(http.StatusOK, tokens)
 }
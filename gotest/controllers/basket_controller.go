@@ -0,0 +1,424 @@
+package controllers
+
+import (
+	"errors"
+	"exchangeapp/global"
+	"exchangeapp/middlewares"
+	"exchangeapp/models/artice"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CreateBasket 创建货币篮子，并根据已有汇率历史计算指数值，当前用户成为篮子的创建者。
+// @Summary 创建货币篮子
+// @Description 创建由多个货币及权重组成的篮子，以 BaseCurrency 计价。
+// @Tags 汇率操作
+// @Accept json
+// @Produce json
+// @Router /api/baskets [post]
+func CreateBasket(ctx *gin.Context) {
+	owner, err := middlewares.CurrentUser(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var basket artice.Basket
+
+	if err := ctx.ShouldBindJSON(&basket); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	basket.OwnerID = owner.ID
+
+	if err := normalizeBasket(&basket); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err = global.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&basket).Error; err != nil {
+			return err
+		}
+		return rebuildBasketValues(tx, &basket)
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, basket)
+}
+
+// GetBaskets 获取所有货币篮子。
+// @Summary 获取货币篮子列表
+// @Tags 汇率操作
+// @Produce json
+// @Router /api/baskets [get]
+func GetBaskets(ctx *gin.Context) {
+	var baskets []artice.Basket
+
+	if err := global.Db.Preload("Components").Find(&baskets).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, baskets)
+}
+
+// GetBasketByID 根据 ID 获取货币篮子。
+// @Summary 获取单个货币篮子
+// @Tags 汇率操作
+// @Param id path string true "篮子ID"
+// @Produce json
+// @Router /api/baskets/{id} [get]
+func GetBasketByID(ctx *gin.Context) {
+	basket, ok := findBasket(ctx)
+	if !ok {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, basket)
+}
+
+// UpdateBasket 更新货币篮子的定义，并重新计算全部指数值，仅创建者或管理员可用。
+// @Summary 更新货币篮子
+// @Tags 汇率操作
+// @Param id path string true "篮子ID"
+// @Accept json
+// @Produce json
+// @Router /api/baskets/{id} [put]
+func UpdateBasket(ctx *gin.Context) {
+	basket, ok := findOwnedBasket(ctx)
+	if !ok {
+		return
+	}
+
+	var input artice.Basket
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := normalizeBasket(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	basket.Name = input.Name
+	basket.BaseCurrency = input.BaseCurrency
+	basket.Description = input.Description
+	basket.Components = input.Components
+	for i := range basket.Components {
+		basket.Components[i].BasketID = basket.ID
+	}
+
+	err := global.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Components").Save(&basket).Error; err != nil {
+			return err
+		}
+		// 成分整体替换，旧成分直接删除
+		if err := tx.Where("basket_id = ?", basket.ID).Delete(&artice.BasketComponent{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&basket.Components).Error; err != nil {
+			return err
+		}
+		return rebuildBasketValues(tx, &basket)
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, basket)
+}
+
+// DeleteBasket 删除货币篮子及其成分和指数值，仅创建者或管理员可用。
+// @Summary 删除货币篮子
+// @Tags 汇率操作
+// @Param id path string true "篮子ID"
+// @Router /api/baskets/{id} [delete]
+func DeleteBasket(ctx *gin.Context) {
+	basket, ok := findOwnedBasket(ctx)
+	if !ok {
+		return
+	}
+
+	err := global.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("basket_id = ?", basket.ID).Delete(&artice.BasketValue{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("basket_id = ?", basket.ID).Delete(&artice.BasketComponent{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&basket).Error
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Successfully deleted the basket"})
+}
+
+// GetBasketValues 获取货币篮子的指数值历史。
+// @Summary 获取篮子指数值
+// @Description 可通过 from/to（RFC3339 或 2006-01-02）限定时间范围。
+// @Tags 汇率操作
+// @Param id path string true "篮子ID"
+// @Param from query string false "开始时间"
+// @Param to query string false "结束时间"
+// @Produce json
+// @Router /api/baskets/{id}/values [get]
+func GetBasketValues(ctx *gin.Context) {
+	basket, ok := findBasket(ctx)
+	if !ok {
+		return
+	}
+
+	query := global.Db.Where("basket_id = ?", basket.ID)
+
+	if from := ctx.Query("from"); from != "" {
+		t, err := parseQueryTime(from)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		query = query.Where("date >= ?", t)
+	}
+	if to := ctx.Query("to"); to != "" {
+		t, err := parseQueryTime(to)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		query = query.Where("date <= ?", t)
+	}
+
+	var values []artice.BasketValue
+	if err := query.Order("date asc, id asc").Find(&values).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, values)
+}
+
+// findBasket 根据路径参数 id 查询篮子，查询失败时直接写入响应
+func findBasket(ctx *gin.Context) (artice.Basket, bool) {
+	var basket artice.Basket
+
+	if err := global.Db.Preload("Components").Where("id = ?", ctx.Param("id")).First(&basket).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return basket, false
+	}
+
+	return basket, true
+}
+
+// findOwnedBasket 查询篮子并校验当前用户是创建者或管理员，失败时直接写入响应
+func findOwnedBasket(ctx *gin.Context) (artice.Basket, bool) {
+	basket, ok := findBasket(ctx)
+	if !ok {
+		return basket, false
+	}
+
+	u, err := middlewares.CurrentUser(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return basket, false
+	}
+
+	if basket.OwnerID != u.ID && !u.IsAdmin() {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "only the owner or an admin can modify this basket"})
+		return basket, false
+	}
+
+	return basket, true
+}
+
+// normalizeBasket 统一货币代码为大写，并拒绝重复的成分货币
+func normalizeBasket(basket *artice.Basket) error {
+	basket.BaseCurrency = strings.ToUpper(strings.TrimSpace(basket.BaseCurrency))
+
+	seen := make(map[string]bool, len(basket.Components))
+	for i := range basket.Components {
+		c := &basket.Components[i]
+		c.ID = 0
+		c.Currency = strings.ToUpper(strings.TrimSpace(c.Currency))
+		if seen[c.Currency] {
+			return fmt.Errorf("duplicate component currency %s", c.Currency)
+		}
+		seen[c.Currency] = true
+	}
+
+	return nil
+}
+
+// parseQueryTime 解析查询参数中的时间，支持 RFC3339 和日期两种格式
+func parseQueryTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02", value, time.Local)
+}
+
+// basketCurrencies 返回篮子中需要换算的成分货币（不含计价货币本身）
+func basketCurrencies(basket *artice.Basket) []string {
+	var currencies []string
+	for _, c := range basket.Components {
+		if c.Currency != basket.BaseCurrency {
+			currencies = append(currencies, c.Currency)
+		}
+	}
+	return currencies
+}
+
+// rateToBase 将一条汇率换算为“某货币兑计价货币”的形式，反向汇率取倒数
+func rateToBase(rate artice.ExchangeRate, base string) (string, float64, bool) {
+	from := strings.ToUpper(rate.FromCurrency)
+	to := strings.ToUpper(rate.ToCurrency)
+
+	switch {
+	case to == base:
+		return from, rate.Rate, true
+	case from == base && rate.Rate != 0:
+		return to, 1 / rate.Rate, true
+	}
+	return "", 0, false
+}
+
+// computeBasketValue 根据各成分兑计价货币的汇率计算指数值，缺少任一汇率时返回 false
+func computeBasketValue(basket *artice.Basket, rates map[string]float64) (float64, bool) {
+	var value float64
+	for _, c := range basket.Components {
+		if c.Currency == basket.BaseCurrency {
+			value += c.Weight
+			continue
+		}
+		rate, ok := rates[c.Currency]
+		if !ok {
+			return 0, false
+		}
+		value += c.Weight * rate
+	}
+	return value, true
+}
+
+// rebuildBasketValues 清空篮子的指数值，并按汇率历史逐条重新计算
+func rebuildBasketValues(tx *gorm.DB, basket *artice.Basket) error {
+	if len(basketCurrencies(basket)) > 0 {
+		return recomputeBasketValues(tx, basket, nil)
+	}
+
+	// 篮子只包含计价货币，指数值恒定
+	if err := tx.Where("basket_id = ?", basket.ID).Delete(&artice.BasketValue{}).Error; err != nil {
+		return err
+	}
+	value, _ := computeBasketValue(basket, nil)
+	return tx.Create(&artice.BasketValue{BasketID: basket.ID, Value: value, Date: time.Now()}).Error
+}
+
+// recomputeBasketValues 删除篮子在 since 及之后的指数值，以 since 之前各成分最新的汇率为起点，
+// 按之后的汇率历史逐条重新计算；since 为 nil 时重新计算全部历史
+func recomputeBasketValues(tx *gorm.DB, basket *artice.Basket, since *time.Time) error {
+	currencies := basketCurrencies(basket)
+
+	values := tx.Where("basket_id = ?", basket.ID)
+	rates := tx.Where("(from_currency IN ? AND to_currency = ?) OR (from_currency = ? AND to_currency IN ?)",
+		currencies, basket.BaseCurrency, basket.BaseCurrency, currencies)
+	latest := make(map[string]float64, len(currencies))
+	if since != nil {
+		values = values.Where("date >= ?", *since)
+		rates = rates.Where("date >= ?", *since)
+		var err error
+		if latest, err = latestBasketRates(tx, basket, *since); err != nil {
+			return err
+		}
+	}
+
+	if err := values.Delete(&artice.BasketValue{}).Error; err != nil {
+		return err
+	}
+
+	var history []artice.ExchangeRate
+	if err := rates.Order("date asc, id asc").Find(&history).Error; err != nil {
+		return err
+	}
+
+	var computed []artice.BasketValue
+	for _, r := range history {
+		currency, rate, ok := rateToBase(r, basket.BaseCurrency)
+		if !ok {
+			continue
+		}
+		latest[currency] = rate
+		if value, ok := computeBasketValue(basket, latest); ok {
+			computed = append(computed, artice.BasketValue{BasketID: basket.ID, Value: value, Date: r.Date})
+		}
+	}
+
+	if len(computed) == 0 {
+		return nil
+	}
+	return tx.CreateInBatches(&computed, 500).Error
+}
+
+// latestBasketRates 查询篮子各成分在 before 之前最新的兑计价货币汇率
+func latestBasketRates(tx *gorm.DB, basket *artice.Basket, before time.Time) (map[string]float64, error) {
+	rates := make(map[string]float64)
+	for _, currency := range basketCurrencies(basket) {
+		var r artice.ExchangeRate
+		err := tx.Where("((from_currency = ? AND to_currency = ?) OR (from_currency = ? AND to_currency = ?)) AND date < ?",
+			currency, basket.BaseCurrency, basket.BaseCurrency, currency, before).
+			Order("date desc, id desc").First(&r).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if c, rate, ok := rateToBase(r, basket.BaseCurrency); ok {
+			rates[c] = rate
+		}
+	}
+	return rates, nil
+}
+
+// refreshBasketValues 在新汇率写入后，重新计算受影响的篮子自该汇率日期起的指数值。
+// 补录的历史汇率会改变其后所有时刻的指数值，因此不能只追加一条
+func refreshBasketValues(tx *gorm.DB, rate artice.ExchangeRate) error {
+	var basketIDs []uint
+	if err := tx.Model(&artice.BasketComponent{}).
+		Joins("JOIN baskets ON baskets.id = basket_components.basket_id AND baskets.deleted_at IS NULL").
+		Where("(baskets.base_currency = ? AND basket_components.currency = ?) OR (baskets.base_currency = ? AND basket_components.currency = ?)",
+			rate.ToCurrency, rate.FromCurrency, rate.FromCurrency, rate.ToCurrency).
+		Distinct().Pluck("basket_components.basket_id", &basketIDs).Error; err != nil {
+		return err
+	}
+	if len(basketIDs) == 0 {
+		return nil
+	}
+
+	var baskets []artice.Basket
+	if err := tx.Preload("Components").Where("id IN ?", basketIDs).Find(&baskets).Error; err != nil {
+		return err
+	}
+
+	for i := range baskets {
+		if err := recomputeBasketValues(tx, &baskets[i], &rate.Date); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,405 @@
+package controllers
+
+import (
+	"errors"
+	"exchangeapp/global"
+	"exchangeapp/middlewares"
+	"exchangeapp/models/artice"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxReadingListName 阅读列表名的最大长度
+const maxReadingListName = 100
+
+// normalizeReadingListName 去除列表名首尾空白并校验长度
+func normalizeReadingListName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", errors.New("list name is required")
+	}
+	if utf8.RuneCountInString(name) > maxReadingListName {
+		return "", errors.New("list name is longer than 100 characters")
+	}
+	return name, nil
+}
+
+// readingListNameTaken 判断当前用户是否已有同名的其他列表
+func readingListNameTaken(userID uint, name string, exceptID uint) (bool, error) {
+	var count int64
+	err := global.Db.Model(&artice.ReadingList{}).
+		Where("user_id = ? AND name = ? AND id <> ?", userID, name, exceptID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// CreateReadingList 创建阅读列表。
+// @Summary 创建阅读列表
+// @Tags 收藏
+// @Accept json
+// @Produce json
+// @Router /api/lists [post]
+func CreateReadingList(ctx *gin.Context) {
+	u, err := middlewares.CurrentUser(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var list artice.ReadingList
+	if err := ctx.ShouldBindJSON(&list); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if list.Name, err = normalizeReadingListName(list.Name); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	list.ID = 0
+	list.UserID = u.ID
+
+	if taken, err := readingListNameTaken(u.ID, list.Name, 0); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	} else if taken {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "a list with this name already exists"})
+		return
+	}
+
+	if err := global.Db.Create(&list).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, list)
+}
+
+// GetReadingLists 获取当前用户的全部阅读列表及其文章数。
+// @Summary 获取阅读列表
+// @Tags 收藏
+// @Produce json
+// @Router /api/lists [get]
+func GetReadingLists(ctx *gin.Context) {
+	u, err := middlewares.CurrentUser(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	lists := []artice.ReadingList{}
+	if err := global.Db.Where("user_id = ?", u.ID).Order("created_at asc, id asc").Find(&lists).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 已删除的文章不计入列表的文章数
+	var counts []struct {
+		ListID uint
+		Count  int64
+	}
+	if err := global.Db.Table("bookmarks").
+		Select("bookmarks.list_id, COUNT(*) AS count").
+		Joins("JOIN articles ON articles.id = bookmarks.article_id AND articles.deleted_at IS NULL").
+		Where("bookmarks.user_id = ?", u.ID).
+		Group("bookmarks.list_id").
+		Scan(&counts).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	byList := make(map[uint]int64, len(counts))
+	for _, c := range counts {
+		byList[c.ListID] = c.Count
+	}
+	for i := range lists {
+		lists[i].Count = byList[lists[i].ID]
+	}
+
+	ctx.JSON(http.StatusOK, lists)
+}
+
+// RenameReadingList 重命名阅读列表。
+// @Summary 重命名阅读列表
+// @Tags 收藏
+// @Param id path string true "列表ID"
+// @Accept json
+// @Produce json
+// @Router /api/lists/{id} [put]
+func RenameReadingList(ctx *gin.Context) {
+	list, ok := findOwnedReadingList(ctx)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Name string `json:"name" binding:"required"`
+	}
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	name, err := normalizeReadingListName(input.Name)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if taken, err := readingListNameTaken(list.UserID, name, list.ID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	} else if taken {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "a list with this name already exists"})
+		return
+	}
+
+	list.Name = name
+	if err := global.Db.Save(&list).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, list)
+}
+
+// DeleteReadingList 删除阅读列表及其中的全部收藏。
+// @Summary 删除阅读列表
+// @Tags 收藏
+// @Param id path string true "列表ID"
+// @Router /api/lists/{id} [delete]
+func DeleteReadingList(ctx *gin.Context) {
+	list, ok := findOwnedReadingList(ctx)
+	if !ok {
+		return
+	}
+
+	err := global.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("list_id = ?", list.ID).Delete(&artice.Bookmark{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&list).Error
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Successfully deleted the list"})
+}
+
+// GetReadingListArticles 分页获取阅读列表中的文章，按收藏时间从新到旧排列。
+// @Summary 获取阅读列表中的文章
+// @Description 已删除或已下线的文章不会出现在结果中。
+// @Tags 收藏
+// @Param id path string true "列表ID"
+// @Param limit query int false "每页数量，默认 20，最大 100"
+// @Param cursor query string false "上一页返回的 nextCursor"
+// @Produce json
+// @Router /api/lists/{id}/articles [get]
+func GetReadingListArticles(ctx *gin.Context) {
+	list, ok := findOwnedReadingList(ctx)
+	if !ok {
+		return
+	}
+
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", strconv.Itoa(defaultArticlePageSize)))
+	if err != nil || limit <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+	limit = min(limit, maxArticlePageSize)
+
+	u, err := middlewares.CurrentUser(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	audience, err := audienceOf(u)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 只返回仍然存在且当前用户可见的文章：自己的文章，或已发布且在可见范围内的文章
+	query := global.Db.Model(&artice.Bookmark{}).
+		Joins("JOIN articles ON articles.id = bookmarks.article_id AND articles.deleted_at IS NULL").
+		Where("bookmarks.list_id = ?", list.ID).
+		Where(global.Db.Where("articles.author_id = ?", list.UserID).
+			Or(global.Db.Where("articles.status = ?", artice.StatusPublished).Scopes(audience.scope)))
+	if cursor := ctx.Query("cursor"); cursor != "" {
+		beforeID, err := strconv.ParseUint(cursor, 10, 64)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+			return
+		}
+		query = query.Where("bookmarks.id < ?", beforeID)
+	}
+
+	var bookmarks []artice.Bookmark
+	if err := query.Select("bookmarks.*").Order("bookmarks.id desc").Limit(limit).Find(&bookmarks).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ids := make([]uint, len(bookmarks))
+	for i, b := range bookmarks {
+		ids[i] = b.ArticleID
+	}
+	var found []artice.Article
+	if len(ids) > 0 {
+		if err := global.Db.Preload("Tags").Where("id IN ?", ids).Find(&found).Error; err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	byID := make(map[uint]artice.Article, len(found))
+	for _, a := range found {
+		byID[a.ID] = a
+	}
+	articles := make([]artice.Article, 0, len(bookmarks))
+	for _, b := range bookmarks {
+		if a, ok := byID[b.ArticleID]; ok {
+			articles = append(articles, a)
+		}
+	}
+
+	if err := annotateArticles(articles, list.UserID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	nextCursor := ""
+	if len(bookmarks) == limit {
+		nextCursor = strconv.FormatUint(uint64(bookmarks[len(bookmarks)-1].ID), 10)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"articles": articles, "nextCursor": nextCursor})
+}
+
+// AddBookmark 把文章加入阅读列表，重复加入是幂等的。
+// @Summary 收藏文章
+// @Tags 收藏
+// @Param id path string true "列表ID"
+// @Param articleId path string true "文章ID"
+// @Router /api/lists/{id}/articles/{articleId} [put]
+func AddBookmark(ctx *gin.Context) {
+	list, ok := findOwnedReadingList(ctx)
+	if !ok {
+		return
+	}
+
+	var article artice.Article
+	if err := global.Db.Where("id = ?", ctx.Param("articleId")).First(&article).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	u, err := middlewares.CurrentUser(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if visible, err := articleVisibleTo(&article, &u); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	} else if !visible {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": gorm.ErrRecordNotFound.Error()})
+		return
+	}
+
+	bookmark := artice.Bookmark{ListID: list.ID, ArticleID: article.ID, UserID: list.UserID}
+	if err := global.Db.Clauses(clause.OnConflict{DoNothing: true}).Create(&bookmark).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Successfully bookmarked the article"})
+}
+
+// RemoveBookmark 把文章移出阅读列表。
+// @Summary 取消收藏
+// @Tags 收藏
+// @Param id path string true "列表ID"
+// @Param articleId path string true "文章ID"
+// @Router /api/lists/{id}/articles/{articleId} [delete]
+func RemoveBookmark(ctx *gin.Context) {
+	list, ok := findOwnedReadingList(ctx)
+	if !ok {
+		return
+	}
+
+	if err := global.Db.Where("list_id = ? AND article_id = ?", list.ID, ctx.Param("articleId")).
+		Delete(&artice.Bookmark{}).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Successfully removed the bookmark"})
+}
+
+// findOwnedReadingList 查询路径参数 id 对应的阅读列表，并校验属于当前用户；失败时直接写入响应。
+// 他人的列表按不存在处理，不暴露列表是否存在。
+func findOwnedReadingList(ctx *gin.Context) (artice.ReadingList, bool) {
+	var list artice.ReadingList
+
+	u, err := middlewares.CurrentUser(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return list, false
+	}
+
+	if err := global.Db.Where("id = ? AND user_id = ?", ctx.Param("id"), u.ID).First(&list).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return list, false
+	}
+	return list, true
+}
+
+// annotateBookmarks 标记当前用户已收藏的文章
+func annotateBookmarks(articles []artice.Article, userID uint) error {
+	if len(articles) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, len(articles))
+	for i, a := range articles {
+		ids[i] = a.ID
+	}
+
+	var bookmarked []uint
+	if err := global.Db.Model(&artice.Bookmark{}).Distinct("article_id").
+		Where("user_id = ? AND article_id IN ?", userID, ids).
+		Pluck("article_id", &bookmarked).Error; err != nil {
+		return err
+	}
+
+	set := make(map[uint]bool, len(bookmarked))
+	for _, id := range bookmarked {
+		set[id] = true
+	}
+	for i := range articles {
+		articles[i].Bookmarked = set[articles[i].ID]
+	}
+	return nil
+}
+
+// annotateArticles 填充文章中与当前用户相关的字段：实时点赞数、是否已点赞、是否已收藏
+func annotateArticles(articles []artice.Article, userID uint) error {
+	if err := annotateLikes(articles, userID); err != nil {
+		return err
+	}
+	return annotateBookmarks(articles, userID)
+}
@@ -0,0 +1,442 @@
+package controllers
+
+import (
+	"errors"
+	"exchangeapp/global"
+	"exchangeapp/middlewares"
+	"exchangeapp/models/artice"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis"
+	"gorm.io/gorm"
+)
+
+// 评论分页参数，按顶级评论（楼层）分页，每个楼层附带完整的回复树
+const (
+	defaultCommentPageSize = 20
+	maxCommentPageSize     = 100
+)
+
+// commentCountKey 生成 Redis 中存储文章评论数的键名，与 article:<id>:likes 并列
+func commentCountKey(articleID uint) string {
+	return fmt.Sprintf("article:%d:comments", articleID)
+}
+
+// CreateComment 发表评论或回复。
+// @Summary 发表评论
+// @Description 传入 parentId 时作为对该评论的回复。
+// @Tags 文章操作
+// @Param id path string true "文章ID"
+// @Accept json
+// @Produce json
+// @Router /api/articles/{id}/comments [post]
+func CreateComment(ctx *gin.Context) {
+	var input struct {
+		Content  string `json:"content" binding:"required"`
+		ParentID *uint  `json:"parentId"`
+	}
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var article artice.Article
+	if err := global.Db.Where("id = ?", ctx.Param("id")).First(&article).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	author, err := middlewares.CurrentUser(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if visible, err := articleVisibleTo(&article, &author); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	} else if !visible {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": gorm.ErrRecordNotFound.Error()})
+		return
+	}
+
+	// 命中敏感词时按策略拒绝、掩码或送审；送审的评论在审核通过前仅作者和管理员可见
+	review, reject := screenTexts(&input.Content)
+	if len(reject) > 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": errSensitiveContent.Error(), "words": reject})
+		return
+	}
+
+	comment := artice.Comment{
+		ArticleID: article.ID,
+		AuthorID:  author.ID,
+		Content:   input.Content,
+		Pending:   len(review) > 0,
+	}
+
+	if input.ParentID != nil {
+		var parent artice.Comment
+		if err := global.Db.Where("id = ? AND article_id = ?", *input.ParentID, article.ID).First(&parent).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": "parent comment not found"})
+			} else {
+				ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			}
+			return
+		}
+		if parent.Deleted {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "cannot reply to a deleted comment"})
+			return
+		}
+		if (parent.Pending || parent.Hidden) && parent.AuthorID != author.ID {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "parent comment not found"})
+			return
+		}
+
+		comment.ParentID = &parent.ID
+		comment.RootID = parent.RootID
+		if comment.RootID == nil {
+			comment.RootID = &parent.ID
+		}
+	}
+
+	// 先确保 Redis 中的评论数已从数据库回填，再在其基础上累加
+	if _, err := articleCommentCount(article.ID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	err = global.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&comment).Error; err != nil {
+			return err
+		}
+		if !comment.Pending {
+			return nil
+		}
+		return syncModeration(tx, artice.ModerationTargetComment, comment.ID, comment.AuthorID, review)
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 待审核的评论在审核通过时才计入评论数和排行榜
+	if comment.Pending {
+		ctx.JSON(http.StatusCreated, comment)
+		return
+	}
+
+	if err := global.RedisDB.Incr(commentCountKey(article.ID)).Err(); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := recordInteraction(article, commentWeight, 1); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	logActivity(activity{Type: activityComment, ActorID: author.ID, ArticleID: article.ID, CommentID: comment.ID}, article)
+
+	ctx.JSON(http.StatusCreated, comment)
+}
+
+// GetArticleComments 分页获取文章的评论树。
+// @Summary 获取文章评论
+// @Description 按顶级评论分页，每条顶级评论附带完整的回复树；已删除的评论以 [deleted] 占位。
+// @Tags 文章操作
+// @Param id path string true "文章ID"
+// @Param limit query int false "每页顶级评论数，默认 20，最大 100"
+// @Param cursor query string false "上一页返回的 nextCursor"
+// @Produce json
+// @Router /api/articles/{id}/comments [get]
+func GetArticleComments(ctx *gin.Context) {
+	articleID, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid article id"})
+		return
+	}
+
+	// 文章的评论与文章本身的可见范围相同
+	var article artice.Article
+	if err := global.Db.Select("id", "author_id", "status", "team_id", "visibility", "visible_roles").First(&article, articleID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	viewer, err := middlewares.CurrentUser(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if visible, err := articleVisibleTo(&article, &viewer); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	} else if !visible {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": gorm.ErrRecordNotFound.Error()})
+		return
+	}
+
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", strconv.Itoa(defaultCommentPageSize)))
+	if err != nil || limit <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+	limit = min(limit, maxCommentPageSize)
+
+	// 待审核和被举报隐藏的评论仅对其作者和管理员可见
+	visible := func(db *gorm.DB) *gorm.DB {
+		if viewer.IsAdmin() {
+			return db
+		}
+		return db.Where("(pending = ? AND hidden = ?) OR author_id = ?", false, false, viewer.ID)
+	}
+
+	query := global.Db.Where("article_id = ? AND parent_id IS NULL", articleID).Scopes(visible)
+	if cursor := ctx.Query("cursor"); cursor != "" {
+		afterID, err := strconv.ParseUint(cursor, 10, 64)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+			return
+		}
+		query = query.Where("id > ?", afterID)
+	}
+
+	var roots []*artice.Comment
+	if err := query.Order("id asc").Limit(limit).Find(&roots).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if len(roots) > 0 {
+		rootIDs := make([]uint, len(roots))
+		for i, root := range roots {
+			rootIDs[i] = root.ID
+		}
+
+		var replies []*artice.Comment
+		if err := global.Db.Where("root_id IN ?", rootIDs).Scopes(visible).Order("id asc").Find(&replies).Error; err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		buildCommentTree(roots, replies)
+	}
+
+	count, err := articleCommentCount(uint(articleID))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	nextCursor := ""
+	if len(roots) == limit {
+		nextCursor = strconv.FormatUint(uint64(roots[len(roots)-1].ID), 10)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"comments": roots, "count": count, "nextCursor": nextCursor})
+}
+
+// UpdateComment 编辑评论，仅评论作者可操作。
+// @Summary 编辑评论
+// @Tags 文章操作
+// @Param id path string true "评论ID"
+// @Accept json
+// @Produce json
+// @Router /api/comments/{id} [put]
+func UpdateComment(ctx *gin.Context) {
+	comment, ok := findOwnedComment(ctx, false)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Content string `json:"content" binding:"required"`
+	}
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	review, reject := screenTexts(&input.Content)
+	if len(reject) > 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": errSensitiveContent.Error(), "words": reject})
+		return
+	}
+
+	// 编辑后命中 review 策略的评论重新送审，不再命中的待审核评论直接公开；被隐藏的评论始终不计数
+	wasCounted := !comment.Pending && !comment.Hidden
+	comment.Content = input.Content
+	comment.Pending = len(review) > 0
+	counted := !comment.Pending && !comment.Hidden
+	if wasCounted != counted {
+		if _, err := articleCommentCount(comment.ArticleID); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	err := global.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&comment).Error; err != nil {
+			return err
+		}
+		return syncModeration(tx, artice.ModerationTargetComment, comment.ID, comment.AuthorID, review)
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if wasCounted != counted {
+		delta := int64(1)
+		if !counted {
+			delta = -1
+		}
+		if err := adjustCommentCount(comment.ArticleID, delta); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	ctx.JSON(http.StatusOK, comment)
+}
+
+// DeleteComment 删除评论，仅评论作者或管理员可操作。评论只做标记，回复保留。
+// @Summary 删除评论
+// @Tags 文章操作
+// @Param id path string true "评论ID"
+// @Router /api/comments/{id} [delete]
+func DeleteComment(ctx *gin.Context) {
+	comment, ok := findOwnedComment(ctx, true)
+	if !ok {
+		return
+	}
+
+	if err := removeComment(comment); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Successfully deleted the comment"})
+}
+
+// removeComment 软删除评论并撤回其待审核项；计入评论数的评论同时回退评论数和排行榜
+func removeComment(comment artice.Comment) error {
+	if _, err := articleCommentCount(comment.ArticleID); err != nil {
+		return err
+	}
+
+	err := global.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&comment).Updates(map[string]interface{}{"deleted": true, "content": ""}).Error; err != nil {
+			return err
+		}
+		return syncModeration(tx, artice.ModerationTargetComment, comment.ID, comment.AuthorID, nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	// 待审核和被隐藏的评论未计入评论数和排行榜，无需回退
+	if !comment.Pending && !comment.Hidden {
+		return adjustCommentCount(comment.ArticleID, -1)
+	}
+	return nil
+}
+
+// findOwnedComment 查询路径参数 id 对应的未删除评论，并校验当前用户是作者（allowAdmin 时管理员也可）；失败时直接写入响应
+func findOwnedComment(ctx *gin.Context, allowAdmin bool) (artice.Comment, bool) {
+	var comment artice.Comment
+
+	if err := global.Db.Where("id = ? AND deleted = ?", ctx.Param("id"), false).First(&comment).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return comment, false
+	}
+
+	u, err := middlewares.CurrentUser(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return comment, false
+	}
+
+	if comment.AuthorID != u.ID && !(allowAdmin && u.IsAdmin()) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "only the author can modify this comment"})
+		return comment, false
+	}
+
+	return comment, true
+}
+
+// buildCommentTree 把回复挂到各自的上级评论下，并为已删除的评论填充占位内容
+func buildCommentTree(roots []*artice.Comment, replies []*artice.Comment) {
+	nodes := make(map[uint]*artice.Comment, len(roots)+len(replies))
+	for _, c := range roots {
+		nodes[c.ID] = c
+	}
+	for _, c := range replies {
+		nodes[c.ID] = c
+	}
+
+	// replies 按 ID 升序排列，上级评论总是先于其回复出现
+	for _, c := range replies {
+		if parent, ok := nodes[*c.ParentID]; ok {
+			parent.Replies = append(parent.Replies, c)
+		}
+	}
+
+	for _, c := range nodes {
+		if c.Deleted {
+			c.Content = artice.DeletedCommentPlaceholder
+		}
+	}
+}
+
+// articleCommentCount 从 Redis 读取文章的评论数，键不存在时从数据库统计并回填
+func articleCommentCount(articleID uint) (int64, error) {
+	key := commentCountKey(articleID)
+
+	count, err := global.RedisDB.Get(key).Int64()
+	if err == nil {
+		return count, nil
+	} else if err != redis.Nil {
+		return 0, err
+	}
+
+	if err := global.Db.Model(&artice.Comment{}).
+		Where("article_id = ? AND deleted = ? AND pending = ? AND hidden = ?", articleID, false, false, false).
+		Count(&count).Error; err != nil {
+		return 0, err
+	}
+
+	// 仅在键仍不存在时回填，避免覆盖并发的 INCR
+	if err := global.RedisDB.SetNX(key, count, 0).Err(); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// adjustCommentCount 调整 Redis 中的评论数以及文章在排行榜中的分数；文章已删除时只调整评论数。
+// 调用方须在写库前调用 articleCommentCount 确保评论数已回填。
+func adjustCommentCount(articleID uint, delta int64) error {
+	if err := global.RedisDB.IncrBy(commentCountKey(articleID), delta).Err(); err != nil {
+		return err
+	}
+
+	var article artice.Article
+	if err := global.Db.Where("id = ?", articleID).First(&article).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return recordInteraction(article, commentWeight, float64(delta))
+}
@@ -24,6 +24,9 @@ func SendVerificationCode(ctx *gin.Context) {
 		panic(rsp.NewErrorResponse(40001, "请求参数错误: "+err.Error(), req))
 	}
 
+	// 邮箱统一转为小写，与注册时保存的邮箱一致
+	req.Email = utils.NormalizeEmail(req.Email)
+
 	// 验证邮箱格式
 	if &req.Email == nil || !utils.IsValidEmail(req.Email) {
 		panic(rsp.NewErrorResponse(40002, "邮箱格式无效", req))
@@ -1,16 +1,43 @@
 package controllers
 
 import (
-	"errors"
+	"exchangeapp/cache"
+	"exchangeapp/config"
 	"exchangeapp/global"
+	"exchangeapp/middlewares"
 	"exchangeapp/models/artice"
+	"fmt"
+	"log"
 	"net/http"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
+// rateCacheIndex 登记汇率查询缓存键的集合，写入汇率时整体失效
+const rateCacheIndex = "rates:cache-keys"
+
+// rateCacheOptions 汇率列表缓存的参数。共识汇率依赖有效期窗口，缓存时间较短
+var rateCacheOptions = cache.Options{
+	TTL:    time.Minute,
+	Stale:  30 * time.Second,
+	Jitter: 0.1,
+	Index:  rateCacheIndex,
+}
+
+// maxRateClockSkew 导入汇率的日期允许超前服务器时间的幅度，用于容忍客户端时钟误差
+const maxRateClockSkew = 5 * time.Minute
+
+// invalidateRateCache 删除所有汇率查询缓存，失败只记录日志，等待缓存自然过期
+func invalidateRateCache() {
+	if err := cache.InvalidateIndex(rateCacheIndex); err != nil {
+		log.Printf("清除汇率缓存失败: %v", err)
+	}
+}
+
 func CreateExchangeRate(ctx *gin.Context) {
 	var exchangeRate artice.ExchangeRate
 
@@ -21,29 +48,290 @@ func CreateExchangeRate(ctx *gin.Context) {
 
 	exchangeRate.Date = time.Now()
 
+	// 标记汇率来源：默认为当前用户手动录入；管理员可以以白名单中的数据提供方的名义录入
+	creator, err := middlewares.CurrentUser(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	exchangeRate.UserID = &creator.ID
+	switch exchangeRate.SourceType {
+	case "", artice.RateSourceManual:
+		if exchangeRate.Source != "" && !creator.IsAdmin() {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": "only admins can set the source"})
+			return
+		}
+		exchangeRate.SourceType = artice.RateSourceManual
+		exchangeRate.Source = creator.Username
+	case artice.RateSourceProvider:
+		if !creator.IsAdmin() {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": "only admins can record provider rates"})
+			return
+		}
+		if !rateProviderAllowed(exchangeRate.Source) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "unknown provider " + exchangeRate.Source})
+			return
+		}
+	default:
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid sourceType " + exchangeRate.SourceType})
+		return
+	}
+
 	if err := global.Db.AutoMigrate(&exchangeRate); err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	if err := global.Db.Create(&exchangeRate).Error; err != nil {
+	// 写入汇率的同时，为包含该货币对的篮子追加新的指数值
+	err = global.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&exchangeRate).Error; err != nil {
+			return err
+		}
+		return refreshBasketValues(tx, exchangeRate)
+	})
+	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	invalidateRateCache()
+
 	ctx.JSON(http.StatusCreated, exchangeRate)
 }
 
-func GetExchangeRates(ctx *gin.Context) {
-	var exchangeRates []artice.ExchangeRate
+// ImportExchangeRates 批量导入汇率，同一批次的汇率共享一个批次号作为来源。
+// 批次号只用于标记，共识中导入的汇率按导入的用户计为一个来源。
+// @Summary 批量导入汇率
+// @Description 只有管理员可以指定 batch，否则自动生成批次号；未提供 date 的汇率使用当前时间，不接受未来的日期。
+// @Tags 汇率操作
+// @Accept json
+// @Produce json
+// @Router /api/exchangeRates/import [post]
+func ImportExchangeRates(ctx *gin.Context) {
+	var input struct {
+		Batch string                `json:"batch"`
+		Rates []artice.ExchangeRate `json:"rates" binding:"required,min=1,dive"`
+	}
+
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	if err := global.Db.Find(&exchangeRates).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
-		} else {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	creator, err := middlewares.CurrentUser(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	if input.Batch != "" && !creator.IsAdmin() {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "only admins can set the batch"})
+		return
+	}
+	if input.Batch == "" {
+		input.Batch = fmt.Sprintf("import-%d", time.Now().UnixNano())
+	}
+
+	now := time.Now()
+	for i := range input.Rates {
+		r := &input.Rates[i]
+		r.ID = 0
+		r.SourceType = artice.RateSourceImport
+		r.Source = input.Batch
+		r.UserID = &creator.ID
+		if r.Date.IsZero() {
+			r.Date = now
+		}
+		// 未来日期的汇率在共识中永远是该来源的最新值，也不会过期，因此拒绝
+		if r.Date.After(now.Add(maxRateClockSkew)) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("rates[%d]: date is in the future", i)})
+			return
+		}
+	}
+
+	err = global.Db.Transaction(func(tx *gorm.DB) error {
+		// 每个货币对只需从本批最早的汇率日期起重新计算一次篮子指数值
+		earliest := make(map[string]artice.ExchangeRate)
+		for _, r := range input.Rates {
+			if err := tx.Create(&r).Error; err != nil {
+				return err
+			}
+			pair := r.FromCurrency + "/" + r.ToCurrency
+			if e, ok := earliest[pair]; !ok || r.Date.Before(e.Date) {
+				earliest[pair] = r
+			}
 		}
+		for _, r := range earliest {
+			if err := refreshBasketValues(tx, r); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	invalidateRateCache()
+
+	ctx.JSON(http.StatusCreated, gin.H{"batch": input.Batch, "count": len(input.Rates)})
+}
+
+func GetExchangeRates(ctx *gin.Context) {
+	// 开启共识模式时，只发布每个货币对的共识汇率
+	if config.AppConfig.Rates.Consensus.Enabled {
+		GetConsensusRates(ctx)
+		return
+	}
+
+	exchangeRates, err := cache.Get("rates:all", rateCacheOptions, func() ([]artice.ExchangeRate, error) {
+		var exchangeRates []artice.ExchangeRate
+		err := global.Db.Find(&exchangeRates).Error
+		return exchangeRates, err
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 	ctx.JSON(http.StatusOK, exchangeRates)
 }
+
+// GetConsensusRates 获取各货币对的共识汇率。
+// @Summary 获取共识汇率
+// @Description 对每个货币对，取各来源在有效期内的最新汇率的中位数；来源数不足的货币对不发布。
+// @Tags 汇率操作
+// @Param from query string false "源货币"
+// @Param to query string false "目标货币"
+// @Produce json
+// @Router /api/exchangeRates/consensus [get]
+func GetConsensusRates(ctx *gin.Context) {
+	consensus := config.AppConfig.Rates.Consensus
+
+	// 货币代码不区分大小写，统一转为大写后查询，并共享同一个缓存键
+	from := strings.ToUpper(strings.TrimSpace(ctx.Query("from")))
+	to := strings.ToUpper(strings.TrimSpace(ctx.Query("to")))
+
+	key := "rates:consensus:" + from + ":" + to
+	published, err := cache.Get(key, rateCacheOptions, func() ([]artice.ExchangeRate, error) {
+		now := time.Now()
+		query := global.Db.Model(&artice.ExchangeRate{}).Where("date <= ?", now)
+		if consensus.MaxAge > 0 {
+			query = query.Where("date >= ?", now.Add(-consensus.MaxAge))
+		}
+		if from != "" {
+			query = query.Where("UPPER(from_currency) = ?", from)
+		}
+		if to != "" {
+			query = query.Where("UPPER(to_currency) = ?", to)
+		}
+
+		var rates []artice.ExchangeRate
+		if err := query.Order("date desc, id desc").Find(&rates).Error; err != nil {
+			return nil, err
+		}
+		return consensusRates(rates, consensus.MinSources), nil
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, published)
+}
+
+// consensusRates 按货币对分组，取每个来源的最新汇率计算中位数；rates 须按时间倒序排列
+func consensusRates(rates []artice.ExchangeRate, minSources int) []artice.ExchangeRate {
+	if minSources < 1 {
+		minSources = 1
+	}
+
+	type pairRates struct {
+		from, to string
+		latest   time.Time
+		sources  map[string]bool
+		values   []float64
+	}
+
+	pairs := make(map[string]*pairRates)
+	var order []string
+	for _, r := range rates {
+		from := strings.ToUpper(r.FromCurrency)
+		to := strings.ToUpper(r.ToCurrency)
+		key := from + "/" + to
+
+		p, ok := pairs[key]
+		if !ok {
+			p = &pairRates{from: from, to: to, sources: make(map[string]bool)}
+			pairs[key] = p
+			order = append(order, key)
+		}
+
+		// 同一来源只取最新的一条；不在白名单中的提供方不计入
+		if r.SourceType == artice.RateSourceProvider && !rateProviderAllowed(r.Source) {
+			continue
+		}
+		source := rateSourceIdentity(r)
+		if p.sources[source] {
+			continue
+		}
+		// 按时间倒序遍历，第一条计入的汇率即为发布时间，不计入的来源不影响发布时间
+		if len(p.values) == 0 {
+			p.latest = r.Date
+		}
+		p.sources[source] = true
+		p.values = append(p.values, r.Rate)
+	}
+
+	published := make([]artice.ExchangeRate, 0, len(order))
+	for _, key := range order {
+		p := pairs[key]
+		if len(p.values) < minSources {
+			continue
+		}
+		published = append(published, artice.ExchangeRate{
+			FromCurrency: p.from,
+			ToCurrency:   p.to,
+			Rate:         median(p.values),
+			Date:         p.latest,
+			SourceType:   artice.RateSourceConsensus,
+			Source:       fmt.Sprintf("median of %d sources", len(p.values)),
+		})
+	}
+
+	return published
+}
+
+// rateProviderAllowed 判断数据提供方是否在配置的白名单中
+func rateProviderAllowed(name string) bool {
+	for _, p := range config.AppConfig.Rates.Providers {
+		if name != "" && p == name {
+			return true
+		}
+	}
+	return false
+}
+
+// rateSourceIdentity 共识中区分来源的服务端身份：提供方汇率按提供方名称（只有管理员能录入白名单中的提供方），
+// 其余按录入的用户，同一用户手动录入和导入的汇率只计为一个来源
+func rateSourceIdentity(r artice.ExchangeRate) string {
+	if r.SourceType == artice.RateSourceProvider {
+		return "provider:" + r.Source
+	}
+	if r.UserID != nil {
+		return fmt.Sprintf("user:%d", *r.UserID)
+	}
+	return r.SourceType + ":" + r.Source
+}
+
+// median 计算中位数，偶数个值时取中间两个值的平均数
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
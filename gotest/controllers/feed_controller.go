@@ -0,0 +1,236 @@
+package controllers
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"exchangeapp/cache"
+	"exchangeapp/config"
+	"exchangeapp/feed"
+	"exchangeapp/global"
+	"exchangeapp/models/artice"
+	"exchangeapp/models/user"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 订阅中的文章数
+const (
+	defaultFeedSize = 20
+	maxFeedSize     = 50
+)
+
+// GetArticleFeedRSS 以 RSS 2.0 格式订阅已发布的文章。
+// @Summary RSS 订阅
+// @Tags 订阅
+// @Param tag query string false "只包含该标签的文章"
+// @Param author query int false "只包含该作者的文章"
+// @Param content query string false "preview（默认）输出摘要，full 输出渲染后的正文"
+// @Param limit query int false "文章数，默认 20，最大 50"
+// @Produce xml
+// @Router /feeds/articles.rss [get]
+func GetArticleFeedRSS(ctx *gin.Context) {
+	serveArticleFeed(ctx, feed.FormatRSS)
+}
+
+// GetArticleFeedAtom 以 Atom 1.0 格式订阅已发布的文章。
+// @Summary Atom 订阅
+// @Tags 订阅
+// @Param tag query string false "只包含该标签的文章"
+// @Param author query int false "只包含该作者的文章"
+// @Param content query string false "preview（默认）输出摘要，full 输出渲染后的正文"
+// @Param limit query int false "文章数，默认 20，最大 50"
+// @Produce xml
+// @Router /feeds/articles.atom [get]
+func GetArticleFeedAtom(ctx *gin.Context) {
+	serveArticleFeed(ctx, feed.FormatAtom)
+}
+
+// GetArticleFeedJSON 以 JSON Feed 1.1 格式订阅已发布的文章。
+// @Summary JSON Feed 订阅
+// @Tags 订阅
+// @Param tag query string false "只包含该标签的文章"
+// @Param author query int false "只包含该作者的文章"
+// @Param content query string false "preview（默认）输出摘要，full 输出渲染后的正文"
+// @Param limit query int false "文章数，默认 20，最大 50"
+// @Produce json
+// @Router /feeds/articles.json [get]
+func GetArticleFeedJSON(ctx *gin.Context) {
+	serveArticleFeed(ctx, feed.FormatJSON)
+}
+
+// serveArticleFeed 生成或从缓存读取订阅内容，并按 ETag 支持条件请求
+func serveArticleFeed(ctx *gin.Context, format string) {
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", strconv.Itoa(defaultFeedSize)))
+	if err != nil || limit <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+	limit = min(limit, maxFeedSize)
+
+	content := ctx.DefaultQuery("content", "preview")
+	if content != "preview" && content != "full" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid content " + content})
+		return
+	}
+
+	var authorID uint64
+	if author := ctx.Query("author"); author != "" {
+		if authorID, err = strconv.ParseUint(author, 10, 64); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid author"})
+			return
+		}
+	}
+	tag := normalizeTagName(ctx.Query("tag"))
+
+	// 订阅自身的地址只保留影响内容的参数
+	base := siteURL(ctx)
+	params := url.Values{}
+	if tag != "" {
+		params.Set("tag", tag)
+	}
+	if authorID != 0 {
+		params.Set("author", strconv.FormatUint(authorID, 10))
+	}
+	if content != "preview" {
+		params.Set("content", content)
+	}
+	if limit != defaultFeedSize {
+		params.Set("limit", strconv.Itoa(limit))
+	}
+	feedURL := base + ctx.Request.URL.Path
+	if len(params) > 0 {
+		feedURL += "?" + params.Encode()
+	}
+
+	// 订阅与文章列表共用缓存索引，文章变更时一并失效
+	key := articleListCacheKey("feed", format, feedURL)
+	body, err := cache.Get(key, articleCacheOptions(tag), func() ([]byte, error) {
+		return buildArticleFeed(format, base, feedURL, tag, uint(authorID), content == "full", limit)
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "author not found"})
+		return
+	} else if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	sum := sha1.Sum(body)
+	etag := `"` + hex.EncodeToString(sum[:]) + `"`
+	ctx.Header("ETag", etag)
+	ctx.Header("Cache-Control", "public, max-age=300")
+	if match := ctx.GetHeader("If-None-Match"); match != "" && etagMatches(match, etag) {
+		ctx.Status(http.StatusNotModified)
+		return
+	}
+
+	ctx.Data(http.StatusOK, feed.ContentTypes[format], body)
+}
+
+// buildArticleFeed 查询已发布的文章并生成指定格式的订阅内容
+func buildArticleFeed(format, base, feedURL, tag string, authorID uint, full bool, limit int) ([]byte, error) {
+	title := config.AppConfig.App.Name
+	query := global.Db.Model(&artice.Article{}).Where("status = ? AND visibility = ?", artice.StatusPublished, artice.VisibilityPublic)
+
+	if tag != "" {
+		query = query.Where("id IN (?)", global.Db.Table("article_tags").
+			Select("article_tags.article_id").
+			Joins("JOIN tags ON tags.id = article_tags.tag_id").
+			Where("tags.name = ?", tag))
+		title += " #" + tag
+	}
+	if authorID != 0 {
+		var author user.User
+		if err := global.Db.Select("id", "username").First(&author, authorID).Error; err != nil {
+			return nil, err
+		}
+		query = query.Where("author_id = ?", authorID)
+		title += " - " + author.Username
+	}
+
+	var articles []artice.Article
+	if err := query.Preload("Tags").Order("COALESCE(publish_at, created_at) desc, id desc").
+		Limit(limit).Find(&articles).Error; err != nil {
+		return nil, err
+	}
+
+	authorIDs := make([]uint, 0, len(articles))
+	for _, a := range articles {
+		authorIDs = append(authorIDs, a.AuthorID)
+	}
+	var authors []user.User
+	if len(authorIDs) > 0 {
+		if err := global.Db.Select("id", "username").Where("id IN ?", authorIDs).Find(&authors).Error; err != nil {
+			return nil, err
+		}
+	}
+	names := make(map[uint]string, len(authors))
+	for _, u := range authors {
+		names[u.ID] = u.Username
+	}
+
+	f := feed.Feed{
+		Title:       title,
+		Link:        base + "/",
+		FeedURL:     feedURL,
+		Description: "最新发布的文章",
+	}
+	for _, a := range articles {
+		published := a.CreatedAt
+		if a.PublishAt != nil {
+			published = *a.PublishAt
+		}
+		link := fmt.Sprintf("%s/articles/%d", base, a.ID)
+		item := feed.Item{
+			ID:        link,
+			Title:     a.Title,
+			Link:      link,
+			Author:    names[a.AuthorID],
+			Summary:   a.Preview,
+			Published: published,
+			Updated:   a.UpdatedAt,
+		}
+		if full {
+			item.HTML = a.ContentHTML
+		}
+		for _, t := range a.Tags {
+			item.Tags = append(item.Tags, t.Name)
+		}
+		if a.UpdatedAt.After(f.Updated) {
+			f.Updated = a.UpdatedAt
+		}
+		f.Items = append(f.Items, item)
+	}
+
+	return f.Render(format)
+}
+
+// siteURL 返回站点的公开地址，未配置时由请求的协议和主机推断
+func siteURL(ctx *gin.Context) string {
+	if base := strings.TrimSuffix(config.AppConfig.App.URL, "/"); base != "" {
+		return base
+	}
+	scheme := "http"
+	if ctx.Request.TLS != nil || ctx.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + ctx.Request.Host
+}
+
+// etagMatches 判断 If-None-Match 中是否包含当前的 ETag，兼容弱校验和通配符
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
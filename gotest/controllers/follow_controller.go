@@ -0,0 +1,331 @@
+package controllers
+
+import (
+	"errors"
+	"exchangeapp/global"
+	"exchangeapp/middlewares"
+	"exchangeapp/models/team"
+	"exchangeapp/models/user"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// findFollowTarget 查询路径参数 id 对应的用户或团队是否存在；失败时直接写入响应
+func findFollowTarget(ctx *gin.Context, targetType string) (uint, bool) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return 0, false
+	}
+
+	var count int64
+	if targetType == user.FollowTargetUser {
+		err = global.Db.Model(&user.User{}).Where("id = ?", id).Count(&count).Error
+	} else {
+		err = global.Db.Model(&team.Team{}).Where("id = ?", id).Count(&count).Error
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return 0, false
+	}
+	if count == 0 {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": gorm.ErrRecordNotFound.Error()})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// followStats 用户或团队的粉丝数，以及当前用户是否已关注
+func followStats(targetType string, targetID, viewerID uint) (followers int64, following bool, err error) {
+	if err = global.Db.Model(&user.Follow{}).Where("target_type = ? AND target_id = ?", targetType, targetID).
+		Count(&followers).Error; err != nil {
+		return
+	}
+	var count int64
+	err = global.Db.Model(&user.Follow{}).
+		Where("follower_id = ? AND target_type = ? AND target_id = ?", viewerID, targetType, targetID).Count(&count).Error
+	return followers, count > 0, err
+}
+
+// FollowUser 关注用户，重复关注是幂等的。
+// @Summary 关注用户
+// @Tags 关注
+// @Param id path int true "用户ID"
+// @Router /api/users/{id}/follow [post]
+func FollowUser(ctx *gin.Context) {
+	setFollow(ctx, user.FollowTargetUser, true)
+}
+
+// UnfollowUser 取消关注用户。
+// @Summary 取消关注用户
+// @Tags 关注
+// @Param id path int true "用户ID"
+// @Router /api/users/{id}/follow [delete]
+func UnfollowUser(ctx *gin.Context) {
+	setFollow(ctx, user.FollowTargetUser, false)
+}
+
+// FollowTeam 关注团队，重复关注是幂等的。关注团队后会收到团队发布的公开文章。
+// @Summary 关注团队
+// @Tags 关注
+// @Param id path int true "团队ID"
+// @Router /api/teams/{id}/follow [post]
+func FollowTeam(ctx *gin.Context) {
+	setFollow(ctx, user.FollowTargetTeam, true)
+}
+
+// UnfollowTeam 取消关注团队。
+// @Summary 取消关注团队
+// @Tags 关注
+// @Param id path int true "团队ID"
+// @Router /api/teams/{id}/follow [delete]
+func UnfollowTeam(ctx *gin.Context) {
+	setFollow(ctx, user.FollowTargetTeam, false)
+}
+
+// setFollow 关注或取消关注用户或团队，返回最新的粉丝数
+func setFollow(ctx *gin.Context, targetType string, follow bool) {
+	u, err := middlewares.CurrentUser(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	targetID, ok := findFollowTarget(ctx, targetType)
+	if !ok {
+		return
+	}
+	if targetType == user.FollowTargetUser && targetID == u.ID {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "you cannot follow yourself"})
+		return
+	}
+
+	where := global.Db.Where("follower_id = ? AND target_type = ? AND target_id = ?", u.ID, targetType, targetID)
+	if follow {
+		var count int64
+		if err := where.Model(&user.Follow{}).Count(&count).Error; err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if count == 0 {
+			if err := global.Db.Create(&user.Follow{FollowerID: u.ID, TargetType: targetType, TargetID: targetID}).Error; err != nil {
+				ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+		}
+	} else if err := where.Delete(&user.Follow{}).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	followers, following, err := followStats(targetType, targetID, u.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"followers": followers, "following": following})
+}
+
+// GetUserProfile 获取用户的公开资料，包括粉丝数、关注数以及当前用户是否已关注。
+// @Summary 获取用户资料
+// @Tags 关注
+// @Param id path int true "用户ID"
+// @Produce json
+// @Router /api/users/{id} [get]
+func GetUserProfile(ctx *gin.Context) {
+	viewer, err := middlewares.CurrentUser(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var u user.User
+	if err := global.Db.Select("id", "username", "level", "is_banned", "created_at").
+		Where("id = ?", ctx.Param("id")).First(&u).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	followers, isFollowing, err := followStats(user.FollowTargetUser, u.ID, viewer.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	var following int64
+	if err := global.Db.Model(&user.Follow{}).Where("follower_id = ?", u.ID).Count(&following).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"id":          u.ID,
+		"username":    u.Username,
+		"level":       u.Level,
+		"banned":      u.IsBanned,
+		"createdAt":   u.CreatedAt,
+		"followers":   followers,
+		"following":   following,
+		"isFollowing": isFollowing,
+	})
+}
+
+// GetTeamProfile 获取团队的公开资料，包括粉丝数以及当前用户是否已关注。
+// @Summary 获取团队资料
+// @Tags 关注
+// @Param id path int true "团队ID"
+// @Produce json
+// @Router /api/teams/{id} [get]
+func GetTeamProfile(ctx *gin.Context) {
+	viewer, err := middlewares.CurrentUser(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var t team.Team
+	if err := global.Db.Select("id", "name", "description", "owner_id", "created_at").
+		Where("id = ?", ctx.Param("id")).First(&t).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	followers, isFollowing, err := followStats(user.FollowTargetTeam, t.ID, viewer.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"id":          t.ID,
+		"name":        t.Name,
+		"description": t.Description,
+		"ownerId":     t.OwnerID,
+		"createdAt":   t.CreatedAt,
+		"followers":   followers,
+		"isFollowing": isFollowing,
+	})
+}
+
+// followEntry 粉丝或关注列表中的一项
+type followEntry struct {
+	ID         uint   `json:"id"` // 关注记录的ID，用作分页游标
+	TargetType string `json:"targetType"`
+	TargetID   uint   `json:"targetId"`
+	Name       string `json:"name"` // 用户名或团队名称
+}
+
+// GetFollowers 分页获取用户的粉丝，按关注时间从新到旧排列。
+// @Summary 获取粉丝列表
+// @Tags 关注
+// @Param id path int true "用户ID"
+// @Param limit query int false "每页数量，默认 20，最大 100"
+// @Param cursor query string false "上一页返回的 nextCursor"
+// @Produce json
+// @Router /api/users/{id}/followers [get]
+func GetFollowers(ctx *gin.Context) {
+	listFollows(ctx, true)
+}
+
+// GetFollowing 分页获取用户关注的用户和团队，按关注时间从新到旧排列。
+// @Summary 获取关注列表
+// @Tags 关注
+// @Param id path int true "用户ID"
+// @Param limit query int false "每页数量，默认 20，最大 100"
+// @Param cursor query string false "上一页返回的 nextCursor"
+// @Produce json
+// @Router /api/users/{id}/following [get]
+func GetFollowing(ctx *gin.Context) {
+	listFollows(ctx, false)
+}
+
+// listFollows 分页列出用户的粉丝（followers 为 true）或关注的对象
+func listFollows(ctx *gin.Context, followers bool) {
+	userID, ok := findFollowTarget(ctx, user.FollowTargetUser)
+	if !ok {
+		return
+	}
+
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", strconv.Itoa(defaultArticlePageSize)))
+	if err != nil || limit <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+	limit = min(limit, maxArticlePageSize)
+
+	query := global.Db.Where("follower_id = ?", userID)
+	if followers {
+		query = global.Db.Where("target_type = ? AND target_id = ?", user.FollowTargetUser, userID)
+	}
+	if cursor := ctx.Query("cursor"); cursor != "" {
+		beforeID, err := strconv.ParseUint(cursor, 10, 64)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+			return
+		}
+		query = query.Where("id < ?", beforeID)
+	}
+
+	var follows []user.Follow
+	if err := query.Order("id desc").Limit(limit).Find(&follows).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 批量查询用户名和团队名称
+	var userIDs, teamIDs []uint
+	entries := make([]followEntry, len(follows))
+	for i, f := range follows {
+		entries[i] = followEntry{ID: f.ID, TargetType: f.TargetType, TargetID: f.TargetID}
+		if followers {
+			entries[i].TargetType, entries[i].TargetID = user.FollowTargetUser, f.FollowerID
+		}
+		if entries[i].TargetType == user.FollowTargetUser {
+			userIDs = append(userIDs, entries[i].TargetID)
+		} else {
+			teamIDs = append(teamIDs, entries[i].TargetID)
+		}
+	}
+	names := map[string]map[uint]string{user.FollowTargetUser: {}, user.FollowTargetTeam: {}}
+	if len(userIDs) > 0 {
+		var users []user.User
+		if err := global.Db.Select("id", "username").Where("id IN ?", userIDs).Find(&users).Error; err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		for _, u := range users {
+			names[user.FollowTargetUser][u.ID] = u.Username
+		}
+	}
+	if len(teamIDs) > 0 {
+		var teams []team.Team
+		if err := global.Db.Select("id", "name").Where("id IN ?", teamIDs).Find(&teams).Error; err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		for _, t := range teams {
+			names[user.FollowTargetTeam][t.ID] = t.Name
+		}
+	}
+	for i := range entries {
+		entries[i].Name = names[entries[i].TargetType][entries[i].TargetID]
+	}
+
+	nextCursor := ""
+	if len(follows) == limit {
+		nextCursor = strconv.FormatUint(uint64(follows[len(follows)-1].ID), 10)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"items": entries, "nextCursor": nextCursor})
+}
@@ -1,62 +1,283 @@
 package controllers
 
 import (
+	"errors"
 	"exchangeapp/global"
+	"exchangeapp/middlewares"
+	"exchangeapp/models/artice"
+	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-redis/redis"
+	"gorm.io/gorm"
 )
 
-// LikeArticle 增加文章的点赞数。
+// likesPendingKey 记录尚未同步到 MySQL 的点赞变更，字段为 "<文章ID>:<用户ID>"，值 1 表示点赞、0 表示取消
+const likesPendingKey = "likes:pending"
+
+// likeCountKey 生成 Redis 中存储点赞数的键名；该键存在即表示文章的点赞集合已载入 Redis
+func likeCountKey(articleID uint) string {
+	return fmt.Sprintf("article:%d:likes", articleID)
+}
+
+// likersKey 生成 Redis 中存储点赞用户集合的键名
+func likersKey(articleID uint) string {
+	return fmt.Sprintf("article:%d:likers", articleID)
+}
+
+// everLikedKey 生成 Redis 中记录曾经点赞过文章的用户集合的键名，取消点赞不会移出
+func everLikedKey(articleID uint) string {
+	return fmt.Sprintf("article:%d:ever-liked", articleID)
+}
+
+// errLikesNotLoaded 文章的点赞集合尚未载入 Redis（从未点赞过，或 Redis 被清空）
+var errLikesNotLoaded = errors.New("likes not loaded")
+
+// toggleLikeScript 原子地修改点赞集合、点赞数并记录待同步的变更。
+// KEYS: 点赞集合、点赞数、待同步哈希；ARGV: 用户ID、待同步字段、1 点赞 / 0 取消。
+// 点赞数不存在时返回 -1，表示需要先从数据库载入；否则返回集合是否发生变化。
+var toggleLikeScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[2]) == 0 then
+	return -1
+end
+local changed
+if ARGV[3] == '1' then
+	changed = redis.call('SADD', KEYS[1], ARGV[1])
+else
+	changed = redis.call('SREM', KEYS[1], ARGV[1])
+end
+if changed == 1 then
+	redis.call('SET', KEYS[2], redis.call('SCARD', KEYS[1]))
+	redis.call('HSET', KEYS[3], ARGV[2], ARGV[3])
+end
+return changed
+`)
+
+// loadLikesScript 把数据库中的点赞用户并入集合，并以集合大小作为点赞数。
+// KEYS: 点赞集合、点赞数；ARGV: 用户ID列表。
+var loadLikesScript = redis.NewScript(`
+for i = 1, #ARGV, 1000 do
+	redis.call('SADD', KEYS[1], unpack(ARGV, i, math.min(i + 999, #ARGV)))
+end
+redis.call('SET', KEYS[2], redis.call('SCARD', KEYS[1]))
+return redis.call('GET', KEYS[2])
+`)
+
+// loadArticleLikes 从 likes 表载入文章的点赞用户，用于首次访问或 Redis 清空后的重建
+func loadArticleLikes(articleID uint) error {
+	var userIDs []uint
+	if err := global.Db.Model(&artice.ArticleLike{}).Where("article_id = ?", articleID).
+		Pluck("user_id", &userIDs).Error; err != nil {
+		return err
+	}
+
+	args := make([]interface{}, len(userIDs))
+	for i, id := range userIDs {
+		args[i] = id
+	}
+	return loadLikesScript.Run(global.RedisDB, []string{likersKey(articleID), likeCountKey(articleID)}, args...).Err()
+}
+
+// toggleLike 为用户点赞或取消点赞，返回点赞状态是否发生变化
+func toggleLike(articleID, userID uint, like bool) (bool, error) {
+	keys := []string{likersKey(articleID), likeCountKey(articleID), likesPendingKey}
+	field := fmt.Sprintf("%d:%d", articleID, userID)
+	flag := "0"
+	if like {
+		flag = "1"
+	}
+
+	for attempt := 0; attempt < 2; attempt++ {
+		changed, err := toggleLikeScript.Run(global.RedisDB, keys, userID, field, flag).Int64()
+		if err != nil {
+			return false, err
+		}
+		if changed != -1 {
+			return changed == 1, nil
+		}
+		if err := loadArticleLikes(articleID); err != nil {
+			return false, err
+		}
+	}
+	return false, errLikesNotLoaded
+}
+
+// articleLikeCount 获取文章的点赞数，必要时先从数据库载入
+func articleLikeCount(articleID uint) (int64, error) {
+	count, err := global.RedisDB.Get(likeCountKey(articleID)).Int64()
+	if err != redis.Nil {
+		return count, err
+	}
+
+	if err := loadArticleLikes(articleID); err != nil {
+		return 0, err
+	}
+	return global.RedisDB.Get(likeCountKey(articleID)).Int64()
+}
+
+// findLikeTarget 查询路径参数 id 对应的文章和当前用户；失败时直接写入响应
+func findLikeTarget(ctx *gin.Context) (artice.Article, uint, bool) {
+	var article artice.Article
+
+	if err := global.Db.Where("id = ?", ctx.Param("id")).First(&article).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return article, 0, false
+	}
+
+	u, err := middlewares.CurrentUser(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return article, 0, false
+	}
+
+	// 未发布的文章和团队文章对无权查看的用户如同不存在
+	if visible, err := articleVisibleTo(&article, &u); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return article, 0, false
+	} else if !visible {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": gorm.ErrRecordNotFound.Error()})
+		return article, 0, false
+	}
+
+	return article, u.ID, true
+}
+
+// LikeArticle 点赞文章，同一用户重复点赞不会重复计数。
 // @Summary 点赞文章
-// @Description 根据文章ID，为指定文章增加一次点赞数。
+// @Description 根据文章ID，为指定文章点赞；重复点赞是幂等的。
 // @Tags 文章操作
 // @Param id path string true "文章ID"
 // @Router /articles/{id}/like [post]
 func LikeArticle(ctx *gin.Context) {
-	// 获取文章ID
-	articleID := ctx.Param("id")
-
-	// 生成 Redis 中存储点赞数的键名
-	likeKey := "article:" + articleID + ":likes"
+	article, userID, ok := findLikeTarget(ctx)
+	if !ok {
+		return
+	}
 
-	// 使用 Redis 的 Incr 方法为点赞数加一
-	if err := global.RedisDB.Incr(likeKey).Err(); err != nil {
+	changed, err := toggleLike(article.ID, userID, true)
+	if err != nil {
 		// 如果 Redis 操作失败，返回内部服务器错误
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	// 只有新增的点赞计入排行榜，并产生动态；用户第一次点赞该文章时才计入热度，
+	// 反复取消再点赞不会不断推高热度
+	if changed {
+		first, err := global.RedisDB.SAdd(everLikedKey(article.ID), userID).Result()
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if err := recordRankings(article, likeWeight, 1, first == 1); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		logLikeActivity(userID, article)
+	}
+
 	// 返回成功响应
 	ctx.JSON(http.StatusOK, gin.H{"message": "Successfully liked the article"})
 }
 
+// UnlikeArticle 取消点赞文章。
+// @Summary 取消点赞
+// @Description 根据文章ID，取消当前用户对该文章的点赞；未点赞时不做任何修改。
+// @Tags 文章操作
+// @Param id path string true "文章ID"
+// @Router /articles/{id}/like [delete]
+func UnlikeArticle(ctx *gin.Context) {
+	article, userID, ok := findLikeTarget(ctx)
+	if !ok {
+		return
+	}
+
+	changed, err := toggleLike(article.ID, userID, false)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if changed {
+		if err := recordInteraction(article, likeWeight, -1); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Successfully unliked the article"})
+}
+
 // GetArticleLikes 获取文章的点赞数。
 // @Summary 获取文章点赞数
-// @Description 根据文章ID，获取指定文章的点赞数。
+// @Description 根据文章ID，获取指定文章的点赞数以及当前用户是否已点赞。
 // @Tags 文章操作
 // @Param id path string true "文章ID"
 // @Router /articles/{id}/likes [get]
 func GetArticleLikes(ctx *gin.Context) {
-	// 获取文章ID
-	articleID := ctx.Param("id")
-
-	// 生成 Redis 中存储点赞数的键名
-	likeKey := "article:" + articleID + ":likes"
-
-	// 从 Redis 获取点赞数
-	likes, err := global.RedisDB.Get(likeKey).Result()
+	article, userID, ok := findLikeTarget(ctx)
+	if !ok {
+		return
+	}
 
-	if err == redis.Nil {
-		// 如果键不存在，则设置点赞数为0
-		likes = "0"
-	} else if err != nil {
+	likes, err := articleLikeCount(article.ID)
+	if err != nil {
 		// 如果 Redis 操作失败，返回内部服务器错误
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	likedByMe, err := global.RedisDB.SIsMember(likersKey(article.ID), userID).Result()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
 	// 返回点赞数
-	ctx.JSON(http.StatusOK, gin.H{"likes": likes})
+	ctx.JSON(http.StatusOK, gin.H{"likes": likes, "likedByMe": likedByMe})
+}
+
+// annotateLikes 用 Redis 中的实时点赞数和当前用户的点赞状态填充文章
+func annotateLikes(articles []artice.Article, userID uint) error {
+	if len(articles) == 0 {
+		return nil
+	}
+
+	pipe := global.RedisDB.Pipeline()
+	counts := make([]*redis.StringCmd, len(articles))
+	liked := make([]*redis.BoolCmd, len(articles))
+	for i, article := range articles {
+		counts[i] = pipe.Get(likeCountKey(article.ID))
+		liked[i] = pipe.SIsMember(likersKey(article.ID), userID)
+	}
+	if _, err := pipe.Exec(); err != nil && err != redis.Nil {
+		return err
+	}
+
+	for i := range articles {
+		likes, err := counts[i].Int64()
+		if err == redis.Nil {
+			// 点赞集合尚未载入，单独载入后再判断
+			if likes, err = articleLikeCount(articles[i].ID); err != nil {
+				return err
+			}
+			likedByMe, err := global.RedisDB.SIsMember(likersKey(articles[i].ID), userID).Result()
+			if err != nil {
+				return err
+			}
+			articles[i].Likes = likes
+			articles[i].LikedByMe = likedByMe
+			continue
+		} else if err != nil {
+			return err
+		}
+		articles[i].Likes = likes
+		articles[i].LikedByMe = liked[i].Val()
+	}
+	return nil
 }
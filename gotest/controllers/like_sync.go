@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"exchangeapp/global"
+	"exchangeapp/models/artice"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// StartLikeSync 启动后台任务，定期把 Redis 中的点赞变更同步到 MySQL
+func StartLikeSync(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := SyncLikes(); err != nil {
+				log.Printf("点赞同步失败: %v", err)
+			}
+		}
+	}()
+}
+
+// SyncLikes 把待同步的点赞变更写入 likes 表，并刷新相关文章在 MySQL 中的点赞数。
+// 待同步哈希先被原子地改名，同步期间产生的新变更不受影响；写库失败时变更会被放回。
+func SyncLikes() error {
+	pending := fmt.Sprintf("%s:%d", likesPendingKey, time.Now().UnixNano())
+	if err := global.RedisDB.Rename(likesPendingKey, pending).Err(); err != nil {
+		if strings.Contains(err.Error(), "no such key") {
+			return nil
+		}
+		return err
+	}
+
+	changes, err := global.RedisDB.HGetAll(pending).Result()
+	if err != nil {
+		return err
+	}
+
+	if err := applyLikeChanges(changes); err != nil {
+		// 放回未同步的变更；若期间同一用户又有新的变更，以新的为准
+		pipe := global.RedisDB.Pipeline()
+		for field, value := range changes {
+			pipe.HSetNX(likesPendingKey, field, value)
+		}
+		pipe.Del(pending)
+		if _, restoreErr := pipe.Exec(); restoreErr != nil {
+			log.Printf("点赞变更放回失败: %v", restoreErr)
+		}
+		return err
+	}
+
+	return global.RedisDB.Del(pending).Err()
+}
+
+// applyLikeChanges 在一个事务中写入点赞、删除取消的点赞，并重新统计受影响文章的点赞数
+func applyLikeChanges(changes map[string]string) error {
+	var likes []artice.ArticleLike
+	var unlikes []artice.ArticleLike
+	touched := make(map[uint]bool)
+
+	for field, value := range changes {
+		parts := strings.SplitN(field, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		articleID, err1 := strconv.ParseUint(parts[0], 10, 64)
+		userID, err2 := strconv.ParseUint(parts[1], 10, 64)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+
+		like := artice.ArticleLike{ArticleID: uint(articleID), UserID: uint(userID)}
+		if value == "1" {
+			likes = append(likes, like)
+		} else {
+			unlikes = append(unlikes, like)
+		}
+		touched[like.ArticleID] = true
+	}
+
+	return global.Db.Transaction(func(tx *gorm.DB) error {
+		if len(likes) > 0 {
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(&likes, 500).Error; err != nil {
+				return err
+			}
+		}
+		for _, unlike := range unlikes {
+			if err := tx.Where("article_id = ? AND user_id = ?", unlike.ArticleID, unlike.UserID).
+				Delete(&artice.ArticleLike{}).Error; err != nil {
+				return err
+			}
+		}
+		for articleID := range touched {
+			count := tx.Model(&artice.ArticleLike{}).Select("COUNT(*)").Where("article_id = ?", articleID)
+			if err := tx.Model(&artice.Article{}).Where("id = ?", articleID).
+				UpdateColumn("likes", count).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,386 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"exchangeapp/config"
+	"exchangeapp/global"
+	"exchangeapp/middlewares"
+	"exchangeapp/models/artice"
+	"exchangeapp/models/user"
+	"exchangeapp/storage"
+	"fmt"
+	"image"
+	_ "image/gif"  // 注册 GIF 解码器，用于读取图片尺寸
+	_ "image/jpeg" // 注册 JPEG 解码器
+	_ "image/png"  // 注册 PNG 解码器
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 上传限制的默认值，配置中未设置时使用
+const (
+	defaultMaxUploadSize = 5 << 20
+	defaultMaxImageSide  = 8000
+)
+
+// allowedMediaTypes 允许上传的文件类型（按内容嗅探）及保存时使用的扩展名
+var allowedMediaTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// signAttachments 为附件生成签名下载地址
+func signAttachments(attachments []artice.Attachment) error {
+	for i := range attachments {
+		url, err := storage.Default.SignedURL(attachments[i].Key, storage.URLExpiry())
+		if err != nil {
+			return err
+		}
+		attachments[i].URL = url
+	}
+	return nil
+}
+
+// UploadMedia 上传图片，可同时关联到当前用户有权编辑的文章。
+// @Summary 上传图片
+// @Description 按文件内容判断类型，只接受 JPEG、PNG、GIF，并限制文件大小和图片尺寸。返回带签名的下载地址。
+// @Tags 媒体
+// @Accept multipart/form-data
+// @Param file formData file true "图片文件"
+// @Param articleId formData int false "关联的文章ID"
+// @Produce json
+// @Router /api/media [post]
+func UploadMedia(ctx *gin.Context) {
+	cfg := config.AppConfig.Storage
+	maxSize := cfg.MaxSize
+	if maxSize <= 0 {
+		maxSize = defaultMaxUploadSize
+	}
+	maxWidth, maxHeight := cfg.MaxWidth, cfg.MaxHeight
+	if maxWidth <= 0 {
+		maxWidth = defaultMaxImageSide
+	}
+	if maxHeight <= 0 {
+		maxHeight = defaultMaxImageSide
+	}
+
+	u, err := middlewares.CurrentUser(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 为 multipart 的边界和其他字段预留 1MB，超出时解析直接失败，避免读入过大的请求体
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxSize+1<<20)
+	header, err := ctx.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("file is larger than %d bytes", maxSize)})
+		} else {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	if header.Size > maxSize {
+		ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("file is larger than %d bytes", maxSize)})
+		return
+	}
+
+	attachment := artice.Attachment{
+		UserID:   u.ID,
+		Filename: mediaFilename(header.Filename),
+		Size:     header.Size,
+	}
+
+	if value := ctx.PostForm("articleId"); value != "" {
+		var article artice.Article
+		if err := global.Db.Where("id = ?", value).First(&article).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			} else {
+				ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			}
+			return
+		}
+		if article.AuthorID != u.ID && !u.IsAdmin() {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": "only the author or an admin can attach files to this article"})
+			return
+		}
+		attachment.ArticleID = &article.ID
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	// 以文件内容而不是扩展名或客户端声明的类型为准
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	attachment.ContentType = http.DetectContentType(head[:n])
+	ext, ok := allowedMediaTypes[attachment.ContentType]
+	if !ok {
+		ctx.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "unsupported file type " + attachment.ContentType})
+		return
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	imgConfig, _, err := image.DecodeConfig(file)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid image: " + err.Error()})
+		return
+	}
+	if imgConfig.Width > maxWidth || imgConfig.Height > maxHeight {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("image is larger than %dx%d", maxWidth, maxHeight)})
+		return
+	}
+	attachment.Width, attachment.Height = imgConfig.Width, imgConfig.Height
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	attachment.Key, err = mediaKey(ext)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := storage.Default.Put(attachment.Key, file, header.Size, attachment.ContentType); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := global.Db.Create(&attachment).Error; err != nil {
+		// 记录写入失败时删除已上传的文件，避免留下无主文件
+		if delErr := storage.Default.Delete(attachment.Key); delErr != nil {
+			log.Printf("删除上传文件失败: %v", delErr)
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	attachments := []artice.Attachment{attachment}
+	if err := signAttachments(attachments); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, attachments[0])
+}
+
+// GetMedia 获取附件信息和新的签名下载地址。
+// @Summary 获取附件
+// @Description 上传者、管理员，或能查看其关联文章的用户可以获取。
+// @Tags 媒体
+// @Param id path string true "附件ID"
+// @Produce json
+// @Router /api/media/{id} [get]
+func GetMedia(ctx *gin.Context) {
+	attachment, viewer, ok := findAttachment(ctx)
+	if !ok {
+		return
+	}
+
+	if attachment.UserID != viewer.ID && !viewer.IsAdmin() {
+		// 非上传者只能获取已关联到可见文章的附件
+		visible := false
+		if attachment.ArticleID != nil {
+			var article artice.Article
+			err := global.Db.Select("id", "author_id", "status", "team_id", "visibility", "visible_roles").
+				First(&article, *attachment.ArticleID).Error
+			if err == nil {
+				visible, err = articleVisibleTo(&article, &viewer)
+			} else if errors.Is(err, gorm.ErrRecordNotFound) {
+				err = nil
+			}
+			if err != nil {
+				ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		if !visible {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": gorm.ErrRecordNotFound.Error()})
+			return
+		}
+	}
+
+	attachments := []artice.Attachment{attachment}
+	if err := signAttachments(attachments); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, attachments[0])
+}
+
+// DeleteMedia 删除附件，仅上传者或管理员可操作。
+// @Summary 删除附件
+// @Tags 媒体
+// @Param id path string true "附件ID"
+// @Router /api/media/{id} [delete]
+func DeleteMedia(ctx *gin.Context) {
+	attachment, viewer, ok := findAttachment(ctx)
+	if !ok {
+		return
+	}
+	if attachment.UserID != viewer.ID && !viewer.IsAdmin() {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "only the uploader or an admin can delete this file"})
+		return
+	}
+
+	if err := storage.Default.Delete(attachment.Key); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := global.Db.Delete(&attachment).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Successfully deleted the file"})
+}
+
+// GetArticleMedia 获取文章关联的全部附件。
+// @Summary 获取文章附件
+// @Tags 媒体
+// @Param id path string true "文章ID"
+// @Produce json
+// @Router /api/articles/{id}/media [get]
+func GetArticleMedia(ctx *gin.Context) {
+	var article artice.Article
+	if err := global.Db.Select("id", "author_id", "status", "team_id", "visibility", "visible_roles").Where("id = ?", ctx.Param("id")).First(&article).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	viewer, err := middlewares.CurrentUser(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if visible, err := articleVisibleTo(&article, &viewer); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	} else if !visible {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": gorm.ErrRecordNotFound.Error()})
+		return
+	}
+
+	attachments := []artice.Attachment{}
+	if err := global.Db.Where("article_id = ?", article.ID).Order("id asc").Find(&attachments).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := signAttachments(attachments); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, attachments)
+}
+
+// ServeMedia 本地存储的签名下载地址，校验签名和过期时间后返回文件内容。
+// @Summary 下载文件
+// @Tags 媒体
+// @Param key path string true "文件路径"
+// @Param expires query int true "过期时间（Unix 秒）"
+// @Param signature query string true "签名"
+// @Router /media/{key} [get]
+func ServeMedia(ctx *gin.Context) {
+	local, ok := storage.Default.(*storage.LocalStorage)
+	if !ok {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+
+	key := strings.TrimPrefix(ctx.Param("key"), "/")
+	if !local.Verify(key, ctx.Query("expires"), ctx.Query("signature")) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "invalid or expired signature"})
+		return
+	}
+
+	file, err := local.Open(key)
+	if errors.Is(err, storage.ErrNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 文件类型在上传时已校验，这里禁止浏览器再次嗅探
+	ctx.Header("X-Content-Type-Options", "nosniff")
+	ctx.Header("Cache-Control", "private, max-age=300")
+	http.ServeContent(ctx.Writer, ctx.Request, filepath.Base(key), info.ModTime(), file)
+}
+
+// findAttachment 查询路径参数 id 对应的附件和当前用户；失败时直接写入响应
+func findAttachment(ctx *gin.Context) (artice.Attachment, user.User, bool) {
+	var attachment artice.Attachment
+	if err := global.Db.Where("id = ?", ctx.Param("id")).First(&attachment).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return attachment, user.User{}, false
+	}
+
+	u, err := middlewares.CurrentUser(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return attachment, u, false
+	}
+	return attachment, u, true
+}
+
+// mediaKey 生成按年月分目录的随机存储路径
+func mediaKey(ext string) (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return time.Now().Format("2006/01/") + hex.EncodeToString(buf) + ext, nil
+}
+
+// mediaFilename 只保留原始文件名的最后一段，并限制长度
+func mediaFilename(name string) string {
+	name = strings.ToValidUTF8(filepath.Base(strings.ReplaceAll(name, `\`, "/")), "")
+	for len(name) > 255 {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	return name
+}
@@ -0,0 +1,581 @@
+package controllers
+
+import (
+	"errors"
+	"exchangeapp/config"
+	"exchangeapp/global"
+	"exchangeapp/middlewares"
+	"exchangeapp/models/artice"
+	"exchangeapp/models/user"
+	"exchangeapp/search"
+	"exchangeapp/sensitive"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis"
+	"gorm.io/gorm"
+)
+
+// sensitiveVersionKey 词库版本号，管理员修改词库时递增，其他实例据此重新加载
+const sensitiveVersionKey = "sensitive:version"
+
+// errSensitiveContent 内容命中 reject 策略的敏感词
+var errSensitiveContent = errors.New("content contains prohibited words")
+
+var (
+	sensitiveMu      sync.Mutex
+	sensitiveVersion string // 本实例已加载的词库版本
+)
+
+// StartSensitiveWordSync 立即从数据库加载词库，并启动后台任务定期检查其他实例是否修改了词库
+func StartSensitiveWordSync(interval time.Duration) {
+	if err := reloadSensitiveWords(); err != nil {
+		log.Printf("敏感词库加载失败: %v", err)
+	}
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := syncSensitiveWords(); err != nil {
+				log.Printf("敏感词库加载失败: %v", err)
+			}
+		}
+	}()
+}
+
+// syncSensitiveWords 词库版本与本实例已加载的版本不同时重新加载
+func syncSensitiveWords() error {
+	version, err := global.RedisDB.Get(sensitiveVersionKey).Result()
+	if err != nil && err != redis.Nil {
+		return err
+	}
+
+	sensitiveMu.Lock()
+	loaded := sensitiveVersion
+	sensitiveMu.Unlock()
+	if version == loaded {
+		return nil
+	}
+	return reloadSensitiveWords()
+}
+
+// reloadSensitiveWords 从数据库加载词库并替换当前生效的过滤器
+func reloadSensitiveWords() error {
+	sensitiveMu.Lock()
+	defer sensitiveMu.Unlock()
+
+	// 先读取版本号，加载期间的修改会在下一次检查时再次加载
+	version, err := global.RedisDB.Get(sensitiveVersionKey).Result()
+	if err != nil && err != redis.Nil {
+		return err
+	}
+
+	var rows []artice.SensitiveWord
+	if err := global.Db.Find(&rows).Error; err != nil {
+		return err
+	}
+	words := make([]sensitive.Word, len(rows))
+	for i, row := range rows {
+		words[i] = sensitive.Word{Text: row.Word, Policy: row.Policy}
+	}
+
+	sensitive.Replace(sensitive.NewFilter(words))
+	sensitiveVersion = version
+	return nil
+}
+
+// publishSensitiveWords 重新加载本实例的词库，并通知其他实例重新加载
+func publishSensitiveWords() error {
+	if err := global.RedisDB.Incr(sensitiveVersionKey).Err(); err != nil {
+		return err
+	}
+	return reloadSensitiveWords()
+}
+
+// screenArticle 用当前词库检查文章的标题、摘要和正文，命中 reject 策略时写入 400 响应并返回 false。
+// mask 策略的词就地替换为掩码；命中 review 策略且文章将要公开时状态改为待审核，
+// 不再命中的待审核文章恢复为按发布时间确定的状态。返回命中的 review 策略的词。
+func screenArticle(ctx *gin.Context, article *artice.Article) ([]string, bool) {
+	review, reject := screenTexts(&article.Title, &article.Preview, &article.Content)
+	if len(reject) > 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": errSensitiveContent.Error(), "words": reject})
+		return nil, false
+	}
+
+	switch article.Status {
+	case artice.StatusPublished, artice.StatusScheduled:
+		if len(review) > 0 {
+			article.Status = artice.StatusPending
+		}
+	case artice.StatusPending:
+		if len(review) == 0 {
+			if err := applyArticleStatus(article, "", article.PublishAt); err != nil {
+				ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return nil, false
+			}
+		}
+	}
+	return review, true
+}
+
+// screenTexts 检查多段文本，mask 策略的词就地替换，返回去重后的 review 和 reject 策略的词
+func screenTexts(texts ...*string) (review, reject []string) {
+	filter := sensitive.Current()
+	seen := make(map[string]bool)
+	for _, text := range texts {
+		result := filter.Check(*text)
+		*text = result.Text
+		for _, word := range result.Review {
+			if !seen[word] {
+				seen[word] = true
+				review = append(review, word)
+			}
+		}
+		for _, word := range result.Reject {
+			if !seen[word] {
+				seen[word] = true
+				reject = append(reject, word)
+			}
+		}
+	}
+	return review, reject
+}
+
+// syncModeration 更新对象的待审核项：words 非空时用新的审核项替换旧的待审核项，为空时撤回旧的待审核项
+func syncModeration(tx *gorm.DB, targetType string, targetID, authorID uint, words []string) error {
+	if err := tx.Where("target_type = ? AND target_id = ? AND status = ?", targetType, targetID, artice.ModerationPending).
+		Delete(&artice.ModerationItem{}).Error; err != nil {
+		return err
+	}
+	if len(words) == 0 {
+		return nil
+	}
+	return tx.Create(&artice.ModerationItem{
+		TargetType: targetType,
+		TargetID:   targetID,
+		AuthorID:   authorID,
+		Words:      words,
+		Status:     artice.ModerationPending,
+	}).Error
+}
+
+// requireAdmin 校验当前用户是管理员；失败时直接写入响应
+func requireAdmin(ctx *gin.Context) (user.User, bool) {
+	u, err := middlewares.CurrentUser(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return u, false
+	}
+	if !u.IsAdmin() {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "only admins can manage moderation"})
+		return u, false
+	}
+	return u, true
+}
+
+// GetSensitiveWords 获取敏感词库，仅管理员可用。
+// @Summary 获取敏感词库
+// @Tags 内容审核
+// @Produce json
+// @Router /api/sensitive-words [get]
+func GetSensitiveWords(ctx *gin.Context) {
+	if _, ok := requireAdmin(ctx); !ok {
+		return
+	}
+
+	var words []artice.SensitiveWord
+	if err := global.Db.Order("id asc").Find(&words).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, words)
+}
+
+// CreateSensitiveWord 添加敏感词，立即在所有实例生效，仅管理员可用。
+// @Summary 添加敏感词
+// @Description policy 为 mask（掩码）、review（送审）或 reject（拒绝），未指定时使用配置中的默认策略。
+// @Tags 内容审核
+// @Accept json
+// @Produce json
+// @Router /api/sensitive-words [post]
+func CreateSensitiveWord(ctx *gin.Context) {
+	admin, ok := requireAdmin(ctx)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Word   string `json:"word" binding:"required,max=100"`
+		Policy string `json:"policy"`
+	}
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	input.Word = strings.ToLower(strings.TrimSpace(input.Word))
+	if input.Word == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "word is required"})
+		return
+	}
+	if input.Policy == "" {
+		input.Policy = config.AppConfig.Sensitive.DefaultPolicy
+	}
+	if !sensitive.ValidPolicy(input.Policy) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid policy " + input.Policy})
+		return
+	}
+
+	var count int64
+	if err := global.Db.Model(&artice.SensitiveWord{}).Where("word = ?", input.Word).Count(&count).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	} else if count > 0 {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "word already exists"})
+		return
+	}
+
+	word := artice.SensitiveWord{Word: input.Word, Policy: input.Policy, CreatedBy: admin.ID}
+	if err := global.Db.Create(&word).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := publishSensitiveWords(); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, word)
+}
+
+// DeleteSensitiveWord 删除敏感词，仅管理员可用。
+// @Summary 删除敏感词
+// @Tags 内容审核
+// @Param id path int true "敏感词ID"
+// @Router /api/sensitive-words/{id} [delete]
+func DeleteSensitiveWord(ctx *gin.Context) {
+	if _, ok := requireAdmin(ctx); !ok {
+		return
+	}
+
+	result := global.Db.Where("id = ?", ctx.Param("id")).Delete(&artice.SensitiveWord{})
+	if result.Error != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
+		return
+	}
+	if result.RowsAffected == 0 {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": gorm.ErrRecordNotFound.Error()})
+		return
+	}
+
+	if err := publishSensitiveWords(); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Successfully deleted the word"})
+}
+
+// ReloadSensitiveWords 从数据库重新加载词库并通知所有实例，用于直接修改数据库后生效，仅管理员可用。
+// @Summary 重新加载敏感词库
+// @Tags 内容审核
+// @Router /api/sensitive-words/reload [post]
+func ReloadSensitiveWords(ctx *gin.Context) {
+	if _, ok := requireAdmin(ctx); !ok {
+		return
+	}
+
+	if err := publishSensitiveWords(); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Successfully reloaded the word list"})
+}
+
+// moderationEntry 审核队列中的一项及其内容
+type moderationEntry struct {
+	artice.ModerationItem
+	Title   string `json:"title,omitempty"` // 文章标题，评论为空
+	Content string `json:"content"`         // 文章正文或评论内容；对象已删除时为空
+}
+
+// GetModerationQueue 分页获取审核队列，仅管理员可用。
+// @Summary 获取审核队列
+// @Description 默认返回待审核项，按提交时间从早到晚排列。
+// @Tags 内容审核
+// @Param status query string false "pending（默认）、approved 或 rejected"
+// @Param type query string false "article 或 comment"
+// @Param limit query int false "每页数量，默认 20，最大 100"
+// @Param cursor query string false "上一页返回的 nextCursor"
+// @Produce json
+// @Router /api/moderation [get]
+func GetModerationQueue(ctx *gin.Context) {
+	if _, ok := requireAdmin(ctx); !ok {
+		return
+	}
+
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", strconv.Itoa(defaultArticlePageSize)))
+	if err != nil || limit <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+	limit = min(limit, maxArticlePageSize)
+
+	status := ctx.DefaultQuery("status", artice.ModerationPending)
+	if status != artice.ModerationPending && status != artice.ModerationApproved && status != artice.ModerationRejected {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid status " + status})
+		return
+	}
+	query := global.Db.Where("status = ?", status)
+
+	if targetType := ctx.Query("type"); targetType != "" {
+		if targetType != artice.ModerationTargetArticle && targetType != artice.ModerationTargetComment {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid type " + targetType})
+			return
+		}
+		query = query.Where("target_type = ?", targetType)
+	}
+	if cursor := ctx.Query("cursor"); cursor != "" {
+		afterID, err := strconv.ParseUint(cursor, 10, 64)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+			return
+		}
+		query = query.Where("id > ?", afterID)
+	}
+
+	var items []artice.ModerationItem
+	if err := query.Order("id asc").Limit(limit).Find(&items).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 批量附上被审核的内容
+	var articleIDs, commentIDs []uint
+	for _, item := range items {
+		if item.TargetType == artice.ModerationTargetArticle {
+			articleIDs = append(articleIDs, item.TargetID)
+		} else {
+			commentIDs = append(commentIDs, item.TargetID)
+		}
+	}
+	var articles []artice.Article
+	if len(articleIDs) > 0 {
+		if err := global.Db.Select("id", "title", "content").Where("id IN ?", articleIDs).Find(&articles).Error; err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	var comments []artice.Comment
+	if len(commentIDs) > 0 {
+		if err := global.Db.Select("id", "content").Where("id IN ?", commentIDs).Find(&comments).Error; err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	articleByID := make(map[uint]artice.Article, len(articles))
+	for _, a := range articles {
+		articleByID[a.ID] = a
+	}
+	commentByID := make(map[uint]artice.Comment, len(comments))
+	for _, c := range comments {
+		commentByID[c.ID] = c
+	}
+
+	entries := make([]moderationEntry, len(items))
+	for i, item := range items {
+		entries[i] = moderationEntry{ModerationItem: item}
+		if item.TargetType == artice.ModerationTargetArticle {
+			entries[i].Title = articleByID[item.TargetID].Title
+			entries[i].Content = articleByID[item.TargetID].Content
+		} else {
+			entries[i].Content = commentByID[item.TargetID].Content
+		}
+	}
+
+	nextCursor := ""
+	if len(items) == limit {
+		nextCursor = strconv.FormatUint(uint64(items[len(items)-1].ID), 10)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"items": entries, "nextCursor": nextCursor})
+}
+
+// ApproveModeration 审核通过：文章按发布时间发布或进入定时发布，评论对所有人可见。
+// @Summary 审核通过
+// @Tags 内容审核
+// @Param id path int true "审核项ID"
+// @Router /api/moderation/{id}/approve [post]
+func ApproveModeration(ctx *gin.Context) {
+	reviewModeration(ctx, artice.ModerationApproved)
+}
+
+// RejectModeration 审核驳回：文章退回草稿，评论被删除。
+// @Summary 审核驳回
+// @Tags 内容审核
+// @Param id path int true "审核项ID"
+// @Param reason body string false "驳回原因"
+// @Router /api/moderation/{id}/reject [post]
+func RejectModeration(ctx *gin.Context) {
+	reviewModeration(ctx, artice.ModerationRejected)
+}
+
+// reviewModeration 处理一个待审核项，decision 为 approved 或 rejected；已被处理（包括并发审核）时返回 409
+func reviewModeration(ctx *gin.Context, decision string) {
+	admin, ok := requireAdmin(ctx)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Reason string `json:"reason" binding:"max=255"`
+	}
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&input); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	var item artice.ModerationItem
+	if err := global.Db.Where("id = ?", ctx.Param("id")).First(&item).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	if item.Status != artice.ModerationPending {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "item has already been " + item.Status})
+		return
+	}
+
+	now := time.Now()
+	item.Status = decision
+	item.ReviewerID = &admin.ID
+	item.ReviewedAt = &now
+	item.Reason = input.Reason
+
+	var err error
+	if item.TargetType == artice.ModerationTargetArticle {
+		err = reviewArticle(item)
+	} else {
+		err = reviewComment(item)
+	}
+	if errors.Is(err, errModerationReviewed) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	} else if errors.Is(err, gorm.ErrRecordNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "the reviewed content no longer exists"})
+		return
+	} else if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, item)
+}
+
+// errModerationReviewed 审核项已被其他管理员处理
+var errModerationReviewed = errors.New("item has already been reviewed")
+
+// saveReview 在事务中保存审核结果。只更新仍处于待审核状态的审核项，并发审核同一项时只有一个成功，
+// 其余返回 errModerationReviewed 并回滚事务，避免重复发布文章或重复计入评论数
+func saveReview(tx *gorm.DB, item artice.ModerationItem) error {
+	result := tx.Model(&artice.ModerationItem{}).
+		Where("id = ? AND status = ?", item.ID, artice.ModerationPending).
+		Updates(map[string]interface{}{
+			"status":      item.Status,
+			"reviewer_id": item.ReviewerID,
+			"reviewed_at": item.ReviewedAt,
+			"reason":      item.Reason,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errModerationReviewed
+	}
+	return nil
+}
+
+// reviewArticle 按审核结果更新待审核文章，并保存审核项
+func reviewArticle(item artice.ModerationItem) error {
+	var article artice.Article
+	if err := global.Db.Preload("Tags").Where("id = ? AND status = ?", item.TargetID, artice.StatusPending).
+		First(&article).Error; err != nil {
+		return err
+	}
+
+	if item.Status == artice.ModerationApproved {
+		if err := applyArticleStatus(&article, "", article.PublishAt); err != nil {
+			return err
+		}
+	} else {
+		article.Status = artice.StatusDraft
+	}
+
+	err := global.Db.Transaction(func(tx *gorm.DB) error {
+		if err := saveReview(tx, item); err != nil {
+			return err
+		}
+		return tx.Model(&article).Select("status", "publish_at").Updates(&article).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := search.Default.Index(article); err != nil {
+		return err
+	}
+	invalidateArticleCache(article.Tags...)
+	if article.Status == artice.StatusScheduled {
+		wakePublishScheduler()
+	}
+	announceArticle(article)
+	return nil
+}
+
+// reviewComment 按审核结果更新待审核评论，并保存审核项：通过时计入评论数和排行榜，驳回时标记删除
+func reviewComment(item artice.ModerationItem) error {
+	var comment artice.Comment
+	if err := global.Db.Where("id = ? AND pending = ? AND deleted = ?", item.TargetID, true, false).
+		First(&comment).Error; err != nil {
+		return err
+	}
+
+	updates := map[string]interface{}{"deleted": true, "content": ""}
+	if item.Status == artice.ModerationApproved {
+		updates = map[string]interface{}{"pending": false}
+		// 先确保 Redis 中的评论数已从数据库回填，再在其基础上累加
+		if _, err := articleCommentCount(comment.ArticleID); err != nil {
+			return err
+		}
+	}
+
+	err := global.Db.Transaction(func(tx *gorm.DB) error {
+		if err := saveReview(tx, item); err != nil {
+			return err
+		}
+		return tx.Model(&comment).Updates(updates).Error
+	})
+	// 审核期间被举报隐藏的评论仍不计数，待举报处理后再计入
+	if err != nil || item.Status != artice.ModerationApproved || comment.Hidden {
+		return err
+	}
+	return adjustCommentCount(comment.ArticleID, 1)
+}
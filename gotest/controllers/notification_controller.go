@@ -0,0 +1,134 @@
+package controllers
+
+import (
+	"exchangeapp/global"
+	"exchangeapp/middlewares"
+	"exchangeapp/models/artice"
+	"exchangeapp/websorket"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 通知类型
+const (
+	notificationReportResolved = "report_resolved" // 举报已处理
+)
+
+// pushNotification 把通知推送给在线的用户，不在线的用户在通知列表中查看
+func pushNotification(n artice.Notification) {
+	websorket.SendToUser(n.UserID, gin.H{"type": "notification", "notification": n})
+}
+
+// GetNotifications 分页获取当前用户的通知，按时间从新到旧排列。
+// @Summary 获取通知
+// @Tags 通知
+// @Param unread query bool false "只返回未读的通知"
+// @Param limit query int false "每页数量，默认 20，最大 100"
+// @Param cursor query string false "上一页返回的 nextCursor"
+// @Produce json
+// @Router /api/notifications [get]
+func GetNotifications(ctx *gin.Context) {
+	u, err := middlewares.CurrentUser(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", strconv.Itoa(defaultArticlePageSize)))
+	if err != nil || limit <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+	limit = min(limit, maxArticlePageSize)
+
+	query := global.Db.Where("user_id = ?", u.ID)
+	if ctx.Query("unread") == "true" {
+		query = query.Where("`read` = ?", false)
+	}
+	if cursor := ctx.Query("cursor"); cursor != "" {
+		beforeID, err := strconv.ParseUint(cursor, 10, 64)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+			return
+		}
+		query = query.Where("id < ?", beforeID)
+	}
+
+	var notifications []artice.Notification
+	if err := query.Order("id desc").Limit(limit).Find(&notifications).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var unread int64
+	if err := global.Db.Model(&artice.Notification{}).Where("user_id = ? AND `read` = ?", u.ID, false).
+		Count(&unread).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	nextCursor := ""
+	if len(notifications) == limit {
+		nextCursor = strconv.FormatUint(uint64(notifications[len(notifications)-1].ID), 10)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"notifications": notifications, "unread": unread, "nextCursor": nextCursor})
+}
+
+// MarkNotificationRead 把当前用户的一条通知标记为已读。
+// @Summary 标记通知为已读
+// @Tags 通知
+// @Param id path int true "通知ID"
+// @Router /api/notifications/{id}/read [post]
+func MarkNotificationRead(ctx *gin.Context) {
+	u, err := middlewares.CurrentUser(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	result := global.Db.Model(&artice.Notification{}).Where("id = ? AND user_id = ?", ctx.Param("id"), u.ID).
+		Update("read", true)
+	if result.Error != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
+		return
+	}
+	if result.RowsAffected == 0 {
+		// 已读的通知不会产生更新，需要区分通知不存在的情况
+		var count int64
+		if err := global.Db.Model(&artice.Notification{}).Where("id = ? AND user_id = ?", ctx.Param("id"), u.ID).
+			Count(&count).Error; err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if count == 0 {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": gorm.ErrRecordNotFound.Error()})
+			return
+		}
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Notification marked as read"})
+}
+
+// MarkAllNotificationsRead 把当前用户的所有通知标记为已读。
+// @Summary 全部标记为已读
+// @Tags 通知
+// @Router /api/notifications/read-all [post]
+func MarkAllNotificationsRead(ctx *gin.Context) {
+	u, err := middlewares.CurrentUser(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	result := global.Db.Model(&artice.Notification{}).Where("user_id = ? AND `read` = ?", u.ID, false).Update("read", true)
+	if result.Error != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": result.Error.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"updated": result.RowsAffected})
+}
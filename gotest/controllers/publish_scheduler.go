@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	"errors"
+	"exchangeapp/global"
+	"exchangeapp/models/artice"
+	"exchangeapp/search"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// publishPollInterval 没有待发布文章时调度器的最长等待时间，也用于兜底其他实例新建的定时文章
+const publishPollInterval = time.Minute
+
+// publishWake 有新的定时文章时唤醒调度器，重新计算下一次发布时间
+var publishWake = make(chan struct{}, 1)
+
+// wakePublishScheduler 通知调度器重新检查待发布的文章
+func wakePublishScheduler() {
+	select {
+	case publishWake <- struct{}{}:
+	default:
+	}
+}
+
+// StartPublishScheduler 启动后台任务，在定时文章的 PublishAt 到达时发布，并同时失效列表缓存
+func StartPublishScheduler() {
+	go func() {
+		for {
+			if err := PublishDueArticles(); err != nil {
+				log.Printf("定时发布失败: %v", err)
+			}
+
+			wait := publishPollInterval
+			var next artice.Article
+			err := global.Db.Where("status = ?", artice.StatusScheduled).Order("publish_at asc").First(&next).Error
+			if err == nil && next.PublishAt != nil {
+				wait = min(wait, max(time.Until(*next.PublishAt), 0))
+			} else if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				log.Printf("查询定时文章失败: %v", err)
+			}
+
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-publishWake:
+				timer.Stop()
+			}
+		}
+	}()
+}
+
+// PublishDueArticles 发布所有已到发布时间的定时文章
+func PublishDueArticles() error {
+	var due []artice.Article
+	if err := global.Db.Preload("Tags").
+		Where("status = ? AND publish_at <= ?", artice.StatusScheduled, time.Now()).
+		Find(&due).Error; err != nil {
+		return err
+	}
+
+	for _, article := range due {
+		// 条件更新保证多个实例同时运行时每篇文章只发布一次
+		result := global.Db.Model(&artice.Article{}).
+			Where("id = ? AND status = ?", article.ID, artice.StatusScheduled).
+			Update("status", artice.StatusPublished)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+
+		article.Status = artice.StatusPublished
+		if err := search.Default.Index(article); err != nil {
+			return err
+		}
+		invalidateArticleCache(article.Tags...)
+		announceArticle(article)
+	}
+	return nil
+}
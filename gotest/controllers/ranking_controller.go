@@ -0,0 +1,467 @@
+package controllers
+
+import (
+	"exchangeapp/global"
+	"exchangeapp/middlewares"
+	"exchangeapp/models/artice"
+	"exchangeapp/models/user"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis"
+)
+
+// 互动类型及其在排行中的权重
+const (
+	viewWeight    = 1.0
+	likeWeight    = 3.0
+	commentWeight = 5.0
+)
+
+// hotHalfLife 热度的半衰期：互动的贡献每经过一个半衰期减半
+const hotHalfLife = 24 * time.Hour
+
+// 排行榜在 Redis 中的键名
+const (
+	hotArticlesKey  = "rank:articles:hot"   // 时间衰减的热度，用于热门文章流
+	hotEpochKey     = "rank:articles:epoch" // 热度计算的基准时间（Unix 秒）
+	rankArticlesKey = "rank:articles"       // 文章互动分，后接周期后缀
+	rankAuthorsKey  = "rank:authors"        // 作者互动分，后接周期后缀
+)
+
+// 排行榜周期
+const (
+	periodDaily  = "daily"
+	periodWeekly = "weekly"
+	periodAll    = "all"
+)
+
+// periodKey 返回某个周期在 t 时刻对应的排行榜键名，以及该键的过期时间（0 表示不过期）
+func periodKey(prefix, period string, t time.Time) (string, time.Duration) {
+	switch period {
+	case periodDaily:
+		return fmt.Sprintf("%s:daily:%s", prefix, t.Format("20060102")), 8 * 24 * time.Hour
+	case periodWeekly:
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%s:weekly:%d-W%02d", prefix, year, week), 35 * 24 * time.Hour
+	default:
+		return prefix + ":all", 0
+	}
+}
+
+// hotFactor 计算 t 时刻互动相对基准时间的放大倍数。
+// 采用前向衰减：越新的互动放大越多，已有分数无需随时间改写，排序结果等价于对所有互动做指数衰减。
+func hotFactor(t time.Time, epoch int64) float64 {
+	return math.Exp2(float64(t.Unix()-epoch) / hotHalfLife.Seconds())
+}
+
+// hotEpoch 读取热度的基准时间，不存在时以当前时间初始化
+func hotEpoch() (int64, error) {
+	now := time.Now().Unix()
+	if err := global.RedisDB.SetNX(hotEpochKey, now, 0).Err(); err != nil {
+		return 0, err
+	}
+	return global.RedisDB.Get(hotEpochKey).Int64()
+}
+
+// recordInteraction 记录一次互动：累加文章热度，以及文章和作者在各周期排行榜中的分数。
+// sign 为 -1 时表示撤销（取消点赞、删除评论），只回退各周期排行榜，热度会随时间自然衰减。
+func recordInteraction(article artice.Article, weight float64, sign float64) error {
+	return recordRankings(article, weight, sign, sign > 0)
+}
+
+// recordRankings 同 recordInteraction，hot 为 false 时不累加热度。
+// 热度只增不减，可以反复撤销再重做的互动（如点赞）只有第一次计入热度
+func recordRankings(article artice.Article, weight float64, sign float64, hot bool) error {
+	// 未发布的文章（作者预览草稿等）和团队文章不参与排行
+	if !article.IsPublic() {
+		return nil
+	}
+
+	now := time.Now()
+	member := strconv.FormatUint(uint64(article.ID), 10)
+	author := strconv.FormatUint(uint64(article.AuthorID), 10)
+
+	pipe := global.RedisDB.TxPipeline()
+	if hot {
+		epoch, err := hotEpoch()
+		if err != nil {
+			return err
+		}
+		pipe.ZIncrBy(hotArticlesKey, weight*hotFactor(now, epoch), member)
+	}
+	for _, period := range []string{periodDaily, periodWeekly, periodAll} {
+		key, ttl := periodKey(rankArticlesKey, period, now)
+		pipe.ZIncrBy(key, sign*weight, member)
+		if ttl > 0 {
+			pipe.Expire(key, ttl)
+		}
+		if article.AuthorID != 0 {
+			key, ttl = periodKey(rankAuthorsKey, period, now)
+			pipe.ZIncrBy(key, sign*weight, author)
+			if ttl > 0 {
+				pipe.Expire(key, ttl)
+			}
+		}
+	}
+	_, err := pipe.Exec()
+	return err
+}
+
+// removeFromRankings 把已删除的文章从热度和当前周期的文章排行榜中移除
+func removeFromRankings(articleID uint) error {
+	now := time.Now()
+	member := strconv.FormatUint(uint64(articleID), 10)
+
+	pipe := global.RedisDB.TxPipeline()
+	pipe.ZRem(hotArticlesKey, member)
+	for _, period := range []string{periodDaily, periodWeekly, periodAll} {
+		key, _ := periodKey(rankArticlesKey, period, now)
+		pipe.ZRem(key, member)
+	}
+	_, err := pipe.Exec()
+	return err
+}
+
+// rankingLimit 解析排行榜的 limit 参数
+func rankingLimit(ctx *gin.Context) (int, bool) {
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", strconv.Itoa(defaultArticlePageSize)))
+	if err != nil || limit <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return 0, false
+	}
+	return min(limit, maxArticlePageSize), true
+}
+
+// rankingPeriod 解析排行榜的 period 参数
+func rankingPeriod(ctx *gin.Context) (string, bool) {
+	period := ctx.DefaultQuery("period", periodAll)
+	if period != periodDaily && period != periodWeekly && period != periodAll {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid period " + period})
+		return "", false
+	}
+	return period, true
+}
+
+// GetHotArticles 获取热门文章流。
+// @Summary 热门文章
+// @Description 按时间衰减的热度排序，热度综合点赞、浏览和评论。
+// @Tags 文章操作
+// @Param limit query int false "返回数量，默认 20，最大 100"
+// @Param offset query int false "偏移量"
+// @Produce json
+// @Router /api/articles/hot [get]
+func GetHotArticles(ctx *gin.Context) {
+	limit, ok := rankingLimit(ctx)
+	if !ok {
+		return
+	}
+	offset, err := strconv.Atoi(ctx.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid offset"})
+		return
+	}
+
+	ids, err := global.RedisDB.ZRevRange(hotArticlesKey, int64(offset), int64(offset+limit-1)).Result()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	articles, err := loadArticlesInOrder(ids)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	viewer, err := middlewares.CurrentUser(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if err := annotateArticles(articles, viewer.ID); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, articles)
+}
+
+// GetArticleRanking 获取文章排行榜。
+// @Summary 文章排行榜
+// @Tags 文章操作
+// @Param period query string false "周期：daily、weekly 或 all（默认）"
+// @Param limit query int false "返回数量，默认 20，最大 100"
+// @Produce json
+// @Router /api/rankings/articles [get]
+func GetArticleRanking(ctx *gin.Context) {
+	period, ok := rankingPeriod(ctx)
+	if !ok {
+		return
+	}
+	limit, ok := rankingLimit(ctx)
+	if !ok {
+		return
+	}
+
+	key, _ := periodKey(rankArticlesKey, period, time.Now())
+	entries, err := global.RedisDB.ZRevRangeWithScores(key, 0, int64(limit-1)).Result()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ids := make([]string, len(entries))
+	for i, e := range entries {
+		ids[i] = e.Member.(string)
+	}
+	articles, err := loadArticlesInOrder(ids)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	scores := make(map[uint]float64, len(entries))
+	for _, e := range entries {
+		id, _ := strconv.ParseUint(e.Member.(string), 10, 64)
+		scores[uint(id)] = e.Score
+	}
+
+	ranking := make([]gin.H, 0, len(articles))
+	for _, a := range articles {
+		ranking = append(ranking, gin.H{"id": a.ID, "title": a.Title, "authorId": a.AuthorID, "score": scores[a.ID]})
+	}
+	ctx.JSON(http.StatusOK, ranking)
+}
+
+// GetAuthorRanking 获取作者排行榜。
+// @Summary 作者排行榜
+// @Description 作者的分数为其所有文章获得的互动分之和。
+// @Tags 文章操作
+// @Param period query string false "周期：daily、weekly 或 all（默认）"
+// @Param limit query int false "返回数量，默认 20，最大 100"
+// @Produce json
+// @Router /api/rankings/authors [get]
+func GetAuthorRanking(ctx *gin.Context) {
+	period, ok := rankingPeriod(ctx)
+	if !ok {
+		return
+	}
+	limit, ok := rankingLimit(ctx)
+	if !ok {
+		return
+	}
+
+	key, _ := periodKey(rankAuthorsKey, period, time.Now())
+	entries, err := global.RedisDB.ZRevRangeWithScores(key, 0, int64(limit-1)).Result()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ids := make([]string, len(entries))
+	for i, e := range entries {
+		ids[i] = e.Member.(string)
+	}
+	var users []user.User
+	if err := global.Db.Select("id", "username").Where("id IN ?", ids).Find(&users).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	usernames := make(map[string]string, len(users))
+	for _, u := range users {
+		usernames[strconv.FormatUint(uint64(u.ID), 10)] = u.Username
+	}
+
+	ranking := make([]gin.H, 0, len(entries))
+	for _, e := range entries {
+		id := e.Member.(string)
+		username, ok := usernames[id]
+		if !ok {
+			continue
+		}
+		authorID, _ := strconv.ParseUint(id, 10, 64)
+		ranking = append(ranking, gin.H{"id": authorID, "username": username, "score": e.Score})
+	}
+	ctx.JSON(http.StatusOK, ranking)
+}
+
+// RebuildRankingsHandler 从数据库重新计算所有排行榜，仅管理员可操作。
+// @Summary 重建排行榜
+// @Tags 文章操作
+// @Router /api/rankings/rebuild [post]
+func RebuildRankingsHandler(ctx *gin.Context) {
+	u, err := middlewares.CurrentUser(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if !u.IsAdmin() {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "only admins can rebuild rankings"})
+		return
+	}
+
+	if err := RebuildRankings(); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Successfully rebuilt the rankings"})
+}
+
+// loadArticlesInOrder 按 ids 的顺序加载文章，已删除的文章被跳过
+func loadArticlesInOrder(ids []string) ([]artice.Article, error) {
+	if len(ids) == 0 {
+		return []artice.Article{}, nil
+	}
+
+	var found []artice.Article
+	if err := global.Db.Preload("Tags").Where("id IN ? AND status = ? AND visibility = ?", ids, artice.StatusPublished, artice.VisibilityPublic).Find(&found).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[string]artice.Article, len(found))
+	for _, a := range found {
+		byID[strconv.FormatUint(uint64(a.ID), 10)] = a
+	}
+
+	articles := make([]artice.Article, 0, len(found))
+	for _, id := range ids {
+		if a, ok := byID[id]; ok {
+			articles = append(articles, a)
+		}
+	}
+	return articles, nil
+}
+
+// rankingEvent 重建排行榜时从数据库读取的一次互动
+type rankingEvent struct {
+	ArticleID uint
+	AuthorID  uint
+	CreatedAt time.Time
+	Count     int64 // 同一时间的互动次数，按天汇总的浏览为当天的独立访客数
+}
+
+// RebuildRankings 从数据库中的点赞、评论和浏览统计重新计算热度及各排行榜，并以当前时间前一周作为新的热度基准时间。
+// 新的排行榜先写入临时键，再原子地替换旧键。
+func RebuildRankings() error {
+	now := time.Now()
+	epoch := now.Add(-7 * 24 * time.Hour).Unix()
+
+	hot := make(map[string]float64)
+	articles := make(map[string]map[string]float64)
+	authors := make(map[string]map[string]float64)
+	periodKeys := make(map[string]time.Duration)
+	for _, prefix := range []string{rankArticlesKey, rankAuthorsKey} {
+		for _, period := range []string{periodDaily, periodWeekly, periodAll} {
+			key, ttl := periodKey(prefix, period, now)
+			periodKeys[key] = ttl
+		}
+	}
+	for _, period := range []string{periodDaily, periodWeekly, periodAll} {
+		key, _ := periodKey(rankArticlesKey, period, now)
+		articles[key] = make(map[string]float64)
+		key, _ = periodKey(rankAuthorsKey, period, now)
+		authors[key] = make(map[string]float64)
+	}
+
+	add := func(events []rankingEvent, weight float64) {
+		for _, e := range events {
+			member := strconv.FormatUint(uint64(e.ArticleID), 10)
+			author := strconv.FormatUint(uint64(e.AuthorID), 10)
+			weight := weight * float64(e.Count)
+			hot[member] += weight * hotFactor(e.CreatedAt, epoch)
+			for _, period := range []string{periodDaily, periodWeekly, periodAll} {
+				// 只有发生在当前周期内的互动计入该周期的排行榜
+				key, _ := periodKey(rankArticlesKey, period, e.CreatedAt)
+				if current, ok := articles[key]; ok {
+					current[member] += weight
+				}
+				key, _ = periodKey(rankAuthorsKey, period, e.CreatedAt)
+				if current, ok := authors[key]; ok && e.AuthorID != 0 {
+					current[author] += weight
+				}
+			}
+		}
+	}
+
+	var likes []rankingEvent
+	if err := global.Db.Table("article_likes").
+		Select("article_likes.article_id, articles.author_id, article_likes.created_at, 1 AS count").
+		Joins("JOIN articles ON articles.id = article_likes.article_id AND articles.deleted_at IS NULL AND articles.status = 'published' AND articles.visibility = 'public'").
+		Scan(&likes).Error; err != nil {
+		return err
+	}
+	add(likes, likeWeight)
+
+	var comments []rankingEvent
+	if err := global.Db.Table("comments").
+		Select("comments.article_id, articles.author_id, comments.created_at, 1 AS count").
+		Joins("JOIN articles ON articles.id = comments.article_id AND articles.deleted_at IS NULL AND articles.status = 'published' AND articles.visibility = 'public'").
+		Where("comments.deleted = ?", false).
+		Scan(&comments).Error; err != nil {
+		return err
+	}
+	add(comments, commentWeight)
+
+	// 浏览按天汇总，以当天的独立访客数计分，与实时记录时每位访客每天只计一次保持一致
+	var views []rankingEvent
+	if err := global.Db.Table("article_view_dailies").
+		Select("article_view_dailies.article_id, articles.author_id, article_view_dailies.date AS created_at, article_view_dailies.unique_views AS count").
+		Joins("JOIN articles ON articles.id = article_view_dailies.article_id AND articles.deleted_at IS NULL AND articles.status = 'published' AND articles.visibility = 'public'").
+		Scan(&views).Error; err != nil {
+		return err
+	}
+	add(views, viewWeight)
+
+	suffix := fmt.Sprintf(":rebuild:%d", now.UnixNano())
+	pipe := global.RedisDB.TxPipeline()
+	stage := func(key string, scores map[string]float64, ttl time.Duration) {
+		tmp := key + suffix
+		members := make([]redis.Z, 0, len(scores))
+		for member, score := range scores {
+			members = append(members, redis.Z{Score: score, Member: member})
+		}
+		if len(members) == 0 {
+			pipe.Del(key)
+			return
+		}
+		for i := 0; i < len(members); i += 1000 {
+			pipe.ZAdd(tmp, members[i:min(i+1000, len(members))]...)
+		}
+		pipe.Rename(tmp, key)
+		if ttl > 0 {
+			pipe.Expire(key, ttl)
+		}
+	}
+
+	stage(hotArticlesKey, hot, 0)
+	for key, scores := range articles {
+		stage(key, scores, periodKeys[key])
+	}
+	for key, scores := range authors {
+		stage(key, scores, periodKeys[key])
+	}
+	pipe.Set(hotEpochKey, epoch, 0)
+
+	_, err := pipe.Exec()
+	return err
+}
+
+// StartRankingRebuild 启动后台任务，定期重建排行榜，使热度的基准时间跟随当前时间，避免分数无限增长
+func StartRankingRebuild(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := RebuildRankings(); err != nil {
+				log.Printf("排行榜重建失败: %v", err)
+			}
+		}
+	}()
+}
@@ -0,0 +1,672 @@
+package controllers
+
+import (
+	"errors"
+	"exchangeapp/config"
+	"exchangeapp/global"
+	"exchangeapp/middlewares"
+	"exchangeapp/models/artice"
+	"exchangeapp/models/user"
+	"exchangeapp/search"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 举报的处理方式
+const (
+	reportActionDismiss = "dismiss" // 驳回举报
+	reportActionRemove  = "remove"  // 移除被举报的内容
+	reportActionBan     = "ban"     // 封禁作者并移除被举报的内容
+)
+
+// errReportOwnContent 用户举报了自己或自己发布的内容
+var errReportOwnContent = errors.New("you cannot report yourself or your own content")
+
+// reportTarget 被举报的对象，AuthorID 为内容的作者，举报用户时为该用户本身
+type reportTarget struct {
+	Article  *artice.Article
+	Comment  *artice.Comment
+	User     *user.User
+	AuthorID uint
+}
+
+// findReportTarget 查询举报对象。对象不存在时返回 gorm.ErrRecordNotFound
+func findReportTarget(targetType string, targetID uint) (reportTarget, error) {
+	var target reportTarget
+	switch targetType {
+	case artice.ReportTargetArticle:
+		var article artice.Article
+		if err := global.Db.Preload("Tags").First(&article, targetID).Error; err != nil {
+			return target, err
+		}
+		target.Article, target.AuthorID = &article, article.AuthorID
+	case artice.ReportTargetComment:
+		var comment artice.Comment
+		if err := global.Db.Where("id = ? AND deleted = ?", targetID, false).First(&comment).Error; err != nil {
+			return target, err
+		}
+		target.Comment, target.AuthorID = &comment, comment.AuthorID
+	default:
+		var u user.User
+		if err := global.Db.First(&u, targetID).Error; err != nil {
+			return target, err
+		}
+		target.User, target.AuthorID = &u, u.ID
+	}
+	return target, nil
+}
+
+// reportTargetVisible 判断举报对象对举报人是否可见，举报人只能举报自己能看到的内容
+func reportTargetVisible(target reportTarget, reporter *user.User) (bool, error) {
+	switch {
+	case target.Article != nil:
+		return articleVisibleTo(target.Article, reporter)
+	case target.Comment != nil:
+		if target.Comment.Pending || target.Comment.Hidden {
+			return false, nil
+		}
+		var article artice.Article
+		err := global.Db.Select("id", "author_id", "status", "team_id", "visibility", "visible_roles").
+			First(&article, target.Comment.ArticleID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+		return articleVisibleTo(&article, reporter)
+	}
+	return true, nil
+}
+
+// CreateReport 举报文章、评论或用户。同一用户对同一对象的待处理举报只保留一条，重复举报返回已有的举报；
+// 不同用户的待处理举报达到配置的阈值时，被举报的文章或评论自动隐藏，等待管理员处理。
+// @Summary 举报内容或用户
+// @Tags 举报
+// @Accept json
+// @Produce json
+// @Param targetType body string true "article、comment 或 user"
+// @Param targetId body int true "被举报对象的ID"
+// @Param reason body string true "举报原因"
+// @Router /api/reports [post]
+func CreateReport(ctx *gin.Context) {
+	reporter, err := middlewares.CurrentUser(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var input struct {
+		TargetType string `json:"targetType" binding:"required"`
+		TargetID   uint   `json:"targetId" binding:"required"`
+		Reason     string `json:"reason" binding:"required,max=500"`
+	}
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.TargetType != artice.ReportTargetArticle && input.TargetType != artice.ReportTargetComment &&
+		input.TargetType != artice.ReportTargetUser {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid targetType " + input.TargetType})
+		return
+	}
+
+	target, err := findReportTarget(input.TargetType, input.TargetID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if target.AuthorID == reporter.ID {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": errReportOwnContent.Error()})
+		return
+	}
+	// 不可见的对象按不存在处理，不暴露其存在
+	if visible, err := reportTargetVisible(target, &reporter); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	} else if !visible {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": gorm.ErrRecordNotFound.Error()})
+		return
+	}
+
+	var existing artice.Report
+	err = global.Db.Where("target_type = ? AND target_id = ? AND reporter_id = ? AND status = ?",
+		input.TargetType, input.TargetID, reporter.ID, artice.ReportPending).First(&existing).Error
+	if err == nil {
+		ctx.JSON(http.StatusOK, existing)
+		return
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	report := artice.Report{
+		TargetType: input.TargetType,
+		TargetID:   input.TargetID,
+		ReporterID: reporter.ID,
+		Reason:     input.Reason,
+		Status:     artice.ReportPending,
+	}
+	if err := global.Db.Create(&report).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 自动隐藏失败不影响举报本身，管理员处理时仍可移除内容
+	if err := hideIfOverReported(target, report); err != nil {
+		log.Printf("隐藏被举报的内容失败: %v", err)
+	}
+
+	ctx.JSON(http.StatusCreated, report)
+}
+
+// hideIfOverReported 对象的待处理举报来自足够多的不同用户时隐藏被举报的文章或评论，用户不会被自动处理
+func hideIfOverReported(target reportTarget, report artice.Report) error {
+	threshold := config.AppConfig.Reports.HideThreshold
+	if threshold <= 0 || target.User != nil {
+		return nil
+	}
+
+	var reporters int64
+	err := global.Db.Model(&artice.Report{}).
+		Where("target_type = ? AND target_id = ? AND status = ?", report.TargetType, report.TargetID, artice.ReportPending).
+		Distinct("reporter_id").Count(&reporters).Error
+	if err != nil || reporters < int64(threshold) {
+		return err
+	}
+
+	if target.Article != nil {
+		return hideArticle(*target.Article)
+	}
+	return hideComment(*target.Comment)
+}
+
+// hideArticle 隐藏已发布的文章：从检索索引和文章列表中移除，排行榜查询本身会跳过未发布的文章
+func hideArticle(article artice.Article) error {
+	result := global.Db.Model(&article).Where("status = ?", artice.StatusPublished).Update("status", artice.StatusHidden)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return result.Error
+	}
+	if err := search.Default.Remove(article.ID); err != nil {
+		return err
+	}
+	invalidateArticleCache(article.Tags...)
+	return nil
+}
+
+// hideComment 隐藏评论，计入评论数的评论同时回退评论数和排行榜
+func hideComment(comment artice.Comment) error {
+	// 先确保 Redis 中的评论数已从数据库回填，再在其基础上扣减
+	if _, err := articleCommentCount(comment.ArticleID); err != nil {
+		return err
+	}
+	result := global.Db.Model(&comment).Where("hidden = ?", false).Update("hidden", true)
+	if result.Error != nil || result.RowsAffected == 0 || comment.Pending {
+		return result.Error
+	}
+	return adjustCommentCount(comment.ArticleID, -1)
+}
+
+// unhideArticle 恢复被隐藏的文章，按发布时间重新发布或进入定时发布
+func unhideArticle(article artice.Article) error {
+	if article.Status != artice.StatusHidden {
+		return nil
+	}
+	if err := applyArticleStatus(&article, "", article.PublishAt); err != nil {
+		return err
+	}
+	if err := global.Db.Model(&article).Select("status", "publish_at").Updates(&article).Error; err != nil {
+		return err
+	}
+
+	if err := search.Default.Index(article); err != nil {
+		return err
+	}
+	invalidateArticleCache(article.Tags...)
+	if article.Status == artice.StatusScheduled {
+		wakePublishScheduler()
+	}
+	return nil
+}
+
+// unhideComment 恢复被隐藏的评论，不在审核中的评论重新计入评论数和排行榜
+func unhideComment(comment artice.Comment) error {
+	if !comment.Hidden {
+		return nil
+	}
+	if _, err := articleCommentCount(comment.ArticleID); err != nil {
+		return err
+	}
+	if err := global.Db.Model(&comment).Update("hidden", false).Error; err != nil {
+		return err
+	}
+	if comment.Pending {
+		return nil
+	}
+	return adjustCommentCount(comment.ArticleID, 1)
+}
+
+// reportGroup 举报队列中的一项：同一对象的所有举报汇总在一起
+type reportGroup struct {
+	ID             uint            `json:"id"` // 该对象最早的一条举报的ID，处理时使用
+	TargetType     string          `json:"targetType"`
+	TargetID       uint            `json:"targetId"`
+	Reporters      int64           `json:"reporters"`
+	LastReportedAt time.Time       `json:"lastReportedAt"`
+	Title          string          `json:"title,omitempty"`    // 文章标题
+	Content        string          `json:"content,omitempty"`  // 文章正文或评论内容；对象已删除时为空
+	Username       string          `json:"username,omitempty"` // 被举报的用户或内容作者
+	Hidden         bool            `json:"hidden"`             // 内容是否已被自动隐藏
+	Reports        []artice.Report `json:"reports" gorm:"-"`
+}
+
+// GetReports 分页获取举报队列，同一对象的举报合并为一项，仅管理员可用。
+// @Summary 获取举报队列
+// @Description 默认返回待处理的举报，按对象首次被举报的时间从早到晚排列。
+// @Tags 举报
+// @Param status query string false "pending（默认）、dismissed、removed 或 banned"
+// @Param type query string false "article、comment 或 user"
+// @Param limit query int false "每页数量，默认 20，最大 100"
+// @Param cursor query string false "上一页返回的 nextCursor"
+// @Produce json
+// @Router /api/reports [get]
+func GetReports(ctx *gin.Context) {
+	if _, ok := requireAdmin(ctx); !ok {
+		return
+	}
+
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", strconv.Itoa(defaultArticlePageSize)))
+	if err != nil || limit <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+	limit = min(limit, maxArticlePageSize)
+
+	status := ctx.DefaultQuery("status", artice.ReportPending)
+	switch status {
+	case artice.ReportPending, artice.ReportDismissed, artice.ReportRemoved, artice.ReportBanned:
+	default:
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid status " + status})
+		return
+	}
+	query := global.Db.Model(&artice.Report{}).Where("status = ?", status)
+
+	if targetType := ctx.Query("type"); targetType != "" {
+		if targetType != artice.ReportTargetArticle && targetType != artice.ReportTargetComment &&
+			targetType != artice.ReportTargetUser {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid type " + targetType})
+			return
+		}
+		query = query.Where("target_type = ?", targetType)
+	}
+	query = query.Group("target_type, target_id")
+	if cursor := ctx.Query("cursor"); cursor != "" {
+		afterID, err := strconv.ParseUint(cursor, 10, 64)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+			return
+		}
+		query = query.Having("MIN(id) > ?", afterID)
+	}
+
+	var groups []reportGroup
+	err = query.Select("MIN(id) AS id, target_type, target_id, COUNT(DISTINCT reporter_id) AS reporters, MAX(created_at) AS last_reported_at").
+		Order("id asc").Limit(limit).Scan(&groups).Error
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := describeReportGroups(groups, status); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	nextCursor := ""
+	if len(groups) == limit {
+		nextCursor = strconv.FormatUint(uint64(groups[len(groups)-1].ID), 10)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"items": groups, "nextCursor": nextCursor})
+}
+
+// describeReportGroups 批量附上举报队列中每个对象的举报明细、内容和作者
+func describeReportGroups(groups []reportGroup, status string) error {
+	if len(groups) == 0 {
+		return nil
+	}
+
+	var articleIDs, commentIDs, userIDs []uint
+	for _, g := range groups {
+		switch g.TargetType {
+		case artice.ReportTargetArticle:
+			articleIDs = append(articleIDs, g.TargetID)
+		case artice.ReportTargetComment:
+			commentIDs = append(commentIDs, g.TargetID)
+		default:
+			userIDs = append(userIDs, g.TargetID)
+		}
+	}
+
+	reportQuery := global.Db.Where("status = ?", status)
+	targets := global.Db.Session(&gorm.Session{NewDB: true})
+	for _, g := range groups {
+		targets = targets.Or("target_type = ? AND target_id = ?", g.TargetType, g.TargetID)
+	}
+	var reports []artice.Report
+	if err := reportQuery.Where(targets).Order("id asc").Find(&reports).Error; err != nil {
+		return err
+	}
+
+	var articles []artice.Article
+	if len(articleIDs) > 0 {
+		if err := global.Db.Select("id", "title", "content", "author_id", "status").Where("id IN ?", articleIDs).Find(&articles).Error; err != nil {
+			return err
+		}
+	}
+	var comments []artice.Comment
+	if len(commentIDs) > 0 {
+		if err := global.Db.Select("id", "content", "author_id", "hidden").Where("id IN ?", commentIDs).Find(&comments).Error; err != nil {
+			return err
+		}
+	}
+	articleByID := make(map[uint]artice.Article, len(articles))
+	for _, a := range articles {
+		articleByID[a.ID] = a
+		userIDs = append(userIDs, a.AuthorID)
+	}
+	commentByID := make(map[uint]artice.Comment, len(comments))
+	for _, c := range comments {
+		commentByID[c.ID] = c
+		userIDs = append(userIDs, c.AuthorID)
+	}
+	var users []user.User
+	if err := global.Db.Select("id", "username").Where("id IN ?", userIDs).Find(&users).Error; err != nil {
+		return err
+	}
+	usernames := make(map[uint]string, len(users))
+	for _, u := range users {
+		usernames[u.ID] = u.Username
+	}
+
+	for i := range groups {
+		g := &groups[i]
+		switch g.TargetType {
+		case artice.ReportTargetArticle:
+			a := articleByID[g.TargetID]
+			g.Title, g.Content, g.Username = a.Title, a.Content, usernames[a.AuthorID]
+			g.Hidden = a.Status == artice.StatusHidden
+		case artice.ReportTargetComment:
+			c := commentByID[g.TargetID]
+			g.Content, g.Username, g.Hidden = c.Content, usernames[c.AuthorID], c.Hidden
+		default:
+			g.Username = usernames[g.TargetID]
+		}
+		for _, r := range reports {
+			if r.TargetType == g.TargetType && r.TargetID == g.TargetID {
+				g.Reports = append(g.Reports, r)
+			}
+		}
+	}
+	return nil
+}
+
+// ResolveReport 处理一条举报，同一对象的所有待处理举报一并处理，并通知每位举报人处理结果，仅管理员可用。
+// @Summary 处理举报
+// @Description action 为 dismiss（驳回，恢复被自动隐藏的内容）、remove（移除被举报的文章或评论）
+// @Description 或 ban（封禁被举报的用户或内容作者，并移除被举报的内容）。
+// @Tags 举报
+// @Accept json
+// @Param id path int true "举报ID"
+// @Param action body string true "dismiss、remove 或 ban"
+// @Param note body string false "处理说明，会附在给举报人的通知中"
+// @Router /api/reports/{id}/resolve [post]
+func ResolveReport(ctx *gin.Context) {
+	admin, ok := requireAdmin(ctx)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Action string `json:"action" binding:"required"`
+		Note   string `json:"note" binding:"max=255"`
+	}
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var report artice.Report
+	if err := global.Db.Where("id = ?", ctx.Param("id")).First(&report).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	if report.Status != artice.ReportPending {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "report has already been " + report.Status})
+		return
+	}
+
+	var status string
+	switch input.Action {
+	case reportActionDismiss:
+		status = artice.ReportDismissed
+	case reportActionRemove:
+		if report.TargetType == artice.ReportTargetUser {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "reported users can only be dismissed or banned"})
+			return
+		}
+		status = artice.ReportRemoved
+	case reportActionBan:
+		status = artice.ReportBanned
+	default:
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid action " + input.Action})
+		return
+	}
+
+	// 内容可能已被作者删除，此时驳回和移除只需结束举报；封禁需要知道作者，只能返回 404
+	target, err := findReportTarget(report.TargetType, report.TargetID)
+	found := err == nil
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if input.Action == reportActionBan {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "the reported content no longer exists"})
+			return
+		}
+	} else if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 先认领举报再执行处理，并发处理同一对象的举报时只有一个管理员的处理生效
+	reports, err := claimReports(report, status, admin.ID, input.Note)
+	if errors.Is(err, errReportResolved) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if found {
+		if err := applyResolution(target, input.Action); err != nil {
+			// 处理失败时放回待处理状态，以便重新处理
+			if releaseErr := releaseReports(reports); releaseErr != nil {
+				log.Printf("恢复举报的待处理状态失败: %v", releaseErr)
+			}
+			if errors.Is(err, errBanAdmin) {
+				ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			} else {
+				ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			}
+			return
+		}
+	}
+
+	if err := notifyReporters(reports); err != nil {
+		log.Printf("创建举报处理通知失败: %v", err)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": status, "reports": reports})
+}
+
+// errBanAdmin 管理员不能被封禁
+var errBanAdmin = errors.New("admins cannot be banned")
+
+// banUser 封禁用户并吊销其所有令牌，封禁后不能登录，也不能再刷新令牌
+func banUser(userID uint) error {
+	var u user.User
+	if err := global.Db.First(&u, userID).Error; err != nil {
+		return err
+	}
+	if u.IsAdmin() {
+		return errBanAdmin
+	}
+	if err := global.Db.Model(&u).Update("is_banned", true).Error; err != nil {
+		return err
+	}
+	return revokeUserSessions(u.ID)
+}
+
+// applyReportAction 按处理方式处理被举报的文章或评论：驳回时恢复被隐藏的内容，移除和封禁时删除内容
+func applyReportAction(target reportTarget, action string) error {
+	switch {
+	case target.Article != nil:
+		if action == reportActionDismiss {
+			return unhideArticle(*target.Article)
+		}
+		return removeArticle(*target.Article)
+	case target.Comment != nil:
+		if action == reportActionDismiss {
+			return unhideComment(*target.Comment)
+		}
+		return removeComment(*target.Comment)
+	}
+	return nil
+}
+
+// applyResolution 对被举报的对象执行处理：封禁时先封禁作者，再按处理方式处理内容
+func applyResolution(target reportTarget, action string) error {
+	if action == reportActionBan {
+		if err := banUser(target.AuthorID); err != nil {
+			return err
+		}
+	}
+	return applyReportAction(target, action)
+}
+
+// errReportResolved 举报已被其他管理员处理
+var errReportResolved = errors.New("report has already been resolved")
+
+// claimReports 认领 report 及同一对象的所有待处理举报，把它们标记为处理结果。
+// 只更新仍处于待处理状态的举报，并发处理时只有一个请求认领成功，其余返回 errReportResolved；
+// 同一对象的其他举报在事务中加锁认领，另一位管理员同时处理其中一条时会等待本事务提交后认领失败
+func claimReports(report artice.Report, status string, resolverID uint, note string) ([]artice.Report, error) {
+	now := time.Now()
+	updates := map[string]interface{}{"status": status, "resolver_id": resolverID, "note": note, "resolved_at": now}
+
+	var reports []artice.Report
+	err := global.Db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&artice.Report{}).Where("id = ? AND status = ?", report.ID, artice.ReportPending).Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errReportResolved
+		}
+
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("target_type = ? AND target_id = ? AND status = ?", report.TargetType, report.TargetID, artice.ReportPending).
+			Find(&reports).Error; err != nil {
+			return err
+		}
+		if len(reports) > 0 {
+			ids := make([]uint, len(reports))
+			for i, r := range reports {
+				ids[i] = r.ID
+			}
+			if err := tx.Model(&artice.Report{}).Where("id IN ?", ids).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		reports = append([]artice.Report{report}, reports...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range reports {
+		reports[i].Status = status
+		reports[i].ResolverID = &resolverID
+		reports[i].Note = note
+		reports[i].ResolvedAt = &now
+	}
+	return reports, nil
+}
+
+// releaseReports 处理失败时把认领的举报恢复为待处理
+func releaseReports(reports []artice.Report) error {
+	ids := make([]uint, len(reports))
+	for i, r := range reports {
+		ids[i] = r.ID
+	}
+	return global.Db.Model(&artice.Report{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+		"status": artice.ReportPending, "resolver_id": nil, "note": "", "resolved_at": nil,
+	}).Error
+}
+
+// notifyReporters 为每位举报人创建处理结果的通知；通知会尽量推送给在线的举报人
+func notifyReporters(reports []artice.Report) error {
+	notifications := make([]artice.Notification, 0, len(reports))
+	for _, r := range reports {
+		notifications = append(notifications, artice.Notification{
+			UserID:  r.ReporterID,
+			Type:    notificationReportResolved,
+			Message: reportOutcomeMessage(r),
+		})
+	}
+	if err := global.Db.Create(&notifications).Error; err != nil {
+		return err
+	}
+
+	for _, n := range notifications {
+		pushNotification(n)
+	}
+	return nil
+}
+
+// reportOutcomeMessage 给举报人的处理结果通知
+func reportOutcomeMessage(r artice.Report) string {
+	var outcome string
+	switch r.Status {
+	case artice.ReportDismissed:
+		outcome = "经审核未发现违规，举报已驳回"
+	case artice.ReportRemoved:
+		outcome = "经审核确认违规，相关内容已移除"
+	default:
+		outcome = "经审核确认违规，相关用户已被封禁"
+	}
+
+	targets := map[string]string{
+		artice.ReportTargetArticle: "文章",
+		artice.ReportTargetComment: "评论",
+		artice.ReportTargetUser:    "用户",
+	}
+	msg := fmt.Sprintf("你对%s #%d 的举报已处理：%s。", targets[r.TargetType], r.TargetID, outcome)
+	if r.Note != "" {
+		msg += "处理说明：" + r.Note
+	}
+	return msg
+}
@@ -0,0 +1,251 @@
+package controllers
+
+import (
+	"errors"
+	"exchangeapp/global"
+	"exchangeapp/models/artice"
+	"exchangeapp/search"
+	"exchangeapp/textdiff"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// recordRevision 保存文章当前内容的快照作为新的修订版本，需在保存文章的事务中调用
+func recordRevision(tx *gorm.DB, article artice.Article, editorID uint) error {
+	var last int
+	if err := tx.Model(&artice.ArticleRevision{}).Where("article_id = ?", article.ID).
+		Select("COALESCE(MAX(number), 0)").Scan(&last).Error; err != nil {
+		return err
+	}
+
+	tags := make([]string, len(article.Tags))
+	for i, t := range article.Tags {
+		tags[i] = t.Name
+	}
+
+	// 并发保存时 (article_id, number) 唯一索引冲突会使事务失败，不会产生重复版本
+	return tx.Create(&artice.ArticleRevision{
+		ArticleID:  article.ID,
+		Number:     last + 1,
+		EditorID:   editorID,
+		Title:      article.Title,
+		Content:    article.Content,
+		Preview:    article.Preview,
+		CategoryID: article.CategoryID,
+		Tags:       tags,
+	}).Error
+}
+
+// ensureBaseRevision 为修订历史上线前创建的文章补记修改前的版本，编辑者记为作者
+func ensureBaseRevision(tx *gorm.DB, article artice.Article) error {
+	var count int64
+	if err := tx.Model(&artice.ArticleRevision{}).Where("article_id = ?", article.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	return recordRevision(tx, article, article.AuthorID)
+}
+
+// GetArticleRevisions 获取文章的修订历史，仅作者本人或管理员可查看。
+// @Summary 获取修订历史
+// @Description 按版本号从新到旧返回，不包含正文。
+// @Tags 文章操作
+// @Param id path string true "文章ID"
+// @Produce json
+// @Router /api/articles/{id}/revisions [get]
+func GetArticleRevisions(ctx *gin.Context) {
+	article, _, ok := findOwnedArticle(ctx)
+	if !ok {
+		return
+	}
+
+	var revisions []artice.ArticleRevision
+	if err := global.Db.Omit("content", "preview").Where("article_id = ?", article.ID).
+		Order("number desc").Find(&revisions).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, revisions)
+}
+
+// GetArticleRevision 获取文章的某个修订版本。
+// @Summary 获取修订版本
+// @Tags 文章操作
+// @Param id path string true "文章ID"
+// @Param number path int true "版本号"
+// @Produce json
+// @Router /api/articles/{id}/revisions/{number} [get]
+func GetArticleRevision(ctx *gin.Context) {
+	article, _, ok := findOwnedArticle(ctx)
+	if !ok {
+		return
+	}
+
+	revision, ok := findRevision(ctx, article.ID, ctx.Param("number"))
+	if !ok {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, revision)
+}
+
+// DiffArticleRevisions 比较文章的两个修订版本。
+// @Summary 比较修订版本
+// @Description 分别给出标题、正文和摘要的差异片段，mode 为 line 时按行比较，为 word 时按单词比较。
+// @Description 差异过大时（见 textdiff.MaxTokens、textdiff.MaxEdits）不同的部分整体表示为删除加插入。
+// @Tags 文章操作
+// @Param id path string true "文章ID"
+// @Param from query int true "旧版本号"
+// @Param to query int true "新版本号"
+// @Param mode query string false "比较粒度：line（默认）或 word"
+// @Produce json
+// @Router /api/articles/{id}/revisions/diff [get]
+func DiffArticleRevisions(ctx *gin.Context) {
+	article, _, ok := findOwnedArticle(ctx)
+	if !ok {
+		return
+	}
+
+	mode := ctx.DefaultQuery("mode", "line")
+	if mode != "line" && mode != "word" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid mode " + mode})
+		return
+	}
+
+	from, ok := findRevision(ctx, article.ID, ctx.Query("from"))
+	if !ok {
+		return
+	}
+	to, ok := findRevision(ctx, article.ID, ctx.Query("to"))
+	if !ok {
+		return
+	}
+
+	diff := textdiff.Lines
+	if mode == "word" {
+		diff = textdiff.Words
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"from":    from.Number,
+		"to":      to.Number,
+		"mode":    mode,
+		"title":   textdiff.Words(from.Title, to.Title),
+		"content": diff(from.Content, to.Content),
+		"preview": diff(from.Preview, to.Preview),
+		"tags":    gin.H{"from": from.Tags, "to": to.Tags},
+	})
+}
+
+// RestoreArticleRevision 把文章恢复为某个修订版本的内容，恢复结果作为新的修订版本保存。
+// @Summary 恢复修订版本
+// @Description 恢复标题、正文、摘要、分类和标签，不改变文章的发布状态。
+// @Tags 文章操作
+// @Param id path string true "文章ID"
+// @Param number path int true "版本号"
+// @Produce json
+// @Router /api/articles/{id}/revisions/{number}/restore [post]
+func RestoreArticleRevision(ctx *gin.Context) {
+	article, editor, ok := findOwnedArticle(ctx)
+	if !ok {
+		return
+	}
+
+	revision, ok := findRevision(ctx, article.ID, ctx.Param("number"))
+	if !ok {
+		return
+	}
+
+	previous := article
+	affectedTags := article.Tags
+
+	article.Title = revision.Title
+	article.Content = revision.Content
+	article.Preview = revision.Preview
+	article.CategoryID = revision.CategoryID
+
+	// 词库可能在该版本保存后更新过，恢复的内容同样需要检查
+	review, ok := screenArticle(ctx, &article)
+	if !ok {
+		return
+	}
+	if article.Status != artice.StatusPending {
+		review = nil
+	}
+
+	if err := renderArticle(&article); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := global.Db.Transaction(func(tx *gorm.DB) error {
+		if err := ensureBaseRevision(tx, previous); err != nil {
+			return err
+		}
+		if err := validateCategory(tx, article.CategoryID); err != nil {
+			return err
+		}
+		tags, err := resolveTags(tx, revision.Tags)
+		if err != nil {
+			return err
+		}
+		if err := tx.Omit("Tags").Save(&article).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&article).Association("Tags").Replace(tags); err != nil {
+			return err
+		}
+		article.Tags = tags
+		if err := syncModeration(tx, artice.ModerationTargetArticle, article.ID, article.AuthorID, review); err != nil {
+			return err
+		}
+		return recordRevision(tx, article, editor.ID)
+	})
+	if errors.Is(err, errCategoryNotFound) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": "the category of this revision no longer exists"})
+		return
+	} else if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := search.Default.Index(article); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	invalidateArticleCache(append(affectedTags, article.Tags...)...)
+
+	if article.Status == artice.StatusScheduled {
+		wakePublishScheduler()
+	}
+
+	ctx.JSON(http.StatusOK, article)
+}
+
+// findRevision 按版本号查询文章的修订版本；失败时直接写入响应
+func findRevision(ctx *gin.Context, articleID uint, value string) (artice.ArticleRevision, bool) {
+	var revision artice.ArticleRevision
+
+	number, err := strconv.Atoi(value)
+	if err != nil || number <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid revision number " + value})
+		return revision, false
+	}
+
+	if err := global.Db.Where("article_id = ? AND number = ?", articleID, number).First(&revision).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return revision, false
+	}
+	return revision, true
+}
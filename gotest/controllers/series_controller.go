@@ -0,0 +1,575 @@
+package controllers
+
+import (
+	"errors"
+	"exchangeapp/global"
+	"exchangeapp/middlewares"
+	"exchangeapp/models/artice"
+	"exchangeapp/models/user"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	errArticleInSeries   = errors.New("the article already belongs to a series")
+	errSeriesOrder       = errors.New("articleIds must list every article in the series exactly once")
+	errSeriesArticleMiss = errors.New("the article is not in this series")
+)
+
+// seriesArticleColumns 系列文章列表需要的文章字段，包括判断可见性所需的字段
+var seriesArticleColumns = []string{
+	"articles.id", "articles.title", "articles.preview", "articles.reading_time", "articles.author_id",
+	"articles.status", "articles.publish_at", "articles.team_id", "articles.visibility", "articles.visible_roles",
+}
+
+// seriesArticleIDs 按位置顺序返回系列中所有文章的ID，不检查文章是否存在或可见
+func seriesArticleIDs(tx *gorm.DB, seriesID uint) ([]uint, error) {
+	var ids []uint
+	err := tx.Model(&artice.SeriesArticle{}).Where("series_id = ?", seriesID).
+		Order("position asc, id asc").Pluck("article_id", &ids).Error
+	return ids, err
+}
+
+// renumberSeries 按 ids 的顺序重写系列中文章的位置，位置从 1 开始连续编号
+func renumberSeries(tx *gorm.DB, seriesID uint, ids []uint) error {
+	for i, id := range ids {
+		if err := tx.Model(&artice.SeriesArticle{}).Where("series_id = ? AND article_id = ?", seriesID, id).
+			Update("position", i+1).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// visibleSeriesArticles 按位置顺序返回系列中对 viewer 可见的文章
+func visibleSeriesArticles(seriesID uint, viewer *user.User) ([]artice.Article, error) {
+	var articles []artice.Article
+	if err := global.Db.Select(seriesArticleColumns).
+		Joins("JOIN series_articles ON series_articles.article_id = articles.id").
+		Where("series_articles.series_id = ?", seriesID).
+		Order("series_articles.position asc, series_articles.id asc").
+		Find(&articles).Error; err != nil {
+		return nil, err
+	}
+
+	audience, err := audienceOf(*viewer)
+	if err != nil {
+		return nil, err
+	}
+	visible := articles[:0]
+	for i := range articles {
+		if audience.canView(&articles[i], viewer) {
+			visible = append(visible, articles[i])
+		}
+	}
+	return visible, nil
+}
+
+// seriesNav 返回文章所属系列及其对 viewer 可见的前后篇，文章不属于任何系列时返回 nil
+func seriesNav(article artice.Article, viewer *user.User) (*artice.SeriesNav, error) {
+	var entry artice.SeriesArticle
+	err := global.Db.Where("article_id = ?", article.ID).First(&entry).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var series artice.Series
+	if err := global.Db.First(&series, entry.SeriesID).Error; err != nil {
+		return nil, err
+	}
+	articles, err := visibleSeriesArticles(series.ID, viewer)
+	if err != nil {
+		return nil, err
+	}
+
+	nav := &artice.SeriesNav{ID: series.ID, Title: series.Title, Total: len(articles)}
+	for i, a := range articles {
+		if a.ID != article.ID {
+			continue
+		}
+		nav.Position = i + 1
+		if i > 0 {
+			nav.Prev = &artice.SeriesLink{ID: articles[i-1].ID, Title: articles[i-1].Title}
+		}
+		if i+1 < len(articles) {
+			nav.Next = &artice.SeriesLink{ID: articles[i+1].ID, Title: articles[i+1].Title}
+		}
+	}
+	return nav, nil
+}
+
+// recordSeriesRead 记录读者读过系列中的一篇文章，失败只记录日志
+func recordSeriesRead(userID, seriesID, articleID uint) {
+	read := artice.SeriesRead{UserID: userID, SeriesID: seriesID, ArticleID: articleID}
+	if err := global.Db.Clauses(clause.OnConflict{DoNothing: true}).Create(&read).Error; err != nil {
+		log.Printf("记录系列阅读进度失败: %v", err)
+	}
+}
+
+// findOwnedSeries 查询路径参数 id 对应的系列，并校验当前用户是作者或管理员；失败时直接写入响应
+func findOwnedSeries(ctx *gin.Context) (artice.Series, user.User, bool) {
+	var series artice.Series
+
+	if err := global.Db.Where("id = ?", ctx.Param("id")).First(&series).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return series, user.User{}, false
+	}
+
+	u, err := middlewares.CurrentUser(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return series, u, false
+	}
+
+	if series.AuthorID != u.ID && !u.IsAdmin() {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "only the author or an admin can modify this series"})
+		return series, u, false
+	}
+
+	return series, u, true
+}
+
+// lockSeries 在事务中锁定系列，保证对同一系列的调整依次进行
+func lockSeries(tx *gorm.DB, seriesID uint) error {
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&artice.Series{}, seriesID).Error
+}
+
+// CreateSeries 创建系列，作者为当前用户。
+// @Summary 创建系列
+// @Tags 系列
+// @Accept json
+// @Produce json
+// @Param title body string true "系列标题"
+// @Param description body string false "系列简介"
+// @Router /api/series [post]
+func CreateSeries(ctx *gin.Context) {
+	author, err := middlewares.CurrentUser(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var input struct {
+		Title       string `json:"title" binding:"required,max=200"`
+		Description string `json:"description"`
+	}
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	series := artice.Series{AuthorID: author.ID, Title: input.Title, Description: input.Description}
+	if err := global.Db.Create(&series).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, series)
+}
+
+// UpdateSeries 修改系列的标题和简介，仅作者或管理员可操作。
+// @Summary 修改系列
+// @Tags 系列
+// @Accept json
+// @Produce json
+// @Param id path int true "系列ID"
+// @Router /api/series/{id} [put]
+func UpdateSeries(ctx *gin.Context) {
+	series, _, ok := findOwnedSeries(ctx)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Title       string `json:"title" binding:"required,max=200"`
+		Description string `json:"description"`
+	}
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	series.Title = input.Title
+	series.Description = input.Description
+	if err := global.Db.Save(&series).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, series)
+}
+
+// DeleteSeries 删除系列，系列中的文章保留，仅作者或管理员可操作。
+// @Summary 删除系列
+// @Tags 系列
+// @Param id path int true "系列ID"
+// @Router /api/series/{id} [delete]
+func DeleteSeries(ctx *gin.Context) {
+	series, _, ok := findOwnedSeries(ctx)
+	if !ok {
+		return
+	}
+
+	err := global.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("series_id = ?", series.ID).Delete(&artice.SeriesArticle{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("series_id = ?", series.ID).Delete(&artice.SeriesRead{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&series).Error
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Successfully deleted the series"})
+}
+
+// seriesEntry 系列主页中的一篇文章
+type seriesEntry struct {
+	ID          uint       `json:"id"`
+	Title       string     `json:"title"`
+	Preview     string     `json:"preview"`
+	ReadingTime int        `json:"readingTime"`
+	Status      string     `json:"status"`
+	Position    int        `json:"position"`
+	Read        bool       `json:"read"` // 当前用户是否读过
+	PublishAt   *time.Time `json:"publishAt"`
+}
+
+// GetSeries 系列主页：系列信息、按顺序排列的文章以及当前用户的阅读进度。
+// @Summary 获取系列
+// @Description 只列出当前用户可见的文章；progress 中的 nextArticleId 为第一篇未读的文章，全部读完时为空。
+// @Tags 系列
+// @Param id path int true "系列ID"
+// @Produce json
+// @Router /api/series/{id} [get]
+func GetSeries(ctx *gin.Context) {
+	viewer, err := middlewares.CurrentUser(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var series artice.Series
+	if err := global.Db.Where("id = ?", ctx.Param("id")).First(&series).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	articles, err := visibleSeriesArticles(series.ID, &viewer)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var readIDs []uint
+	if err := global.Db.Model(&artice.SeriesRead{}).Where("user_id = ? AND series_id = ?", viewer.ID, series.ID).
+		Pluck("article_id", &readIDs).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	read := make(map[uint]bool, len(readIDs))
+	for _, id := range readIDs {
+		read[id] = true
+	}
+
+	var author user.User
+	if err := global.Db.Select("id", "username").First(&author, series.AuthorID).Error; err != nil &&
+		!errors.Is(err, gorm.ErrRecordNotFound) {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 进度只统计已发布的文章，作者看到的草稿等不计入
+	entries := make([]seriesEntry, len(articles))
+	var published, readCount int
+	var nextArticleID *uint
+	for i, a := range articles {
+		entries[i] = seriesEntry{
+			ID:          a.ID,
+			Title:       a.Title,
+			Preview:     a.Preview,
+			ReadingTime: a.ReadingTime,
+			Status:      a.Status,
+			Position:    i + 1,
+			Read:        read[a.ID],
+			PublishAt:   a.PublishAt,
+		}
+		if !a.IsPublished() {
+			continue
+		}
+		published++
+		if read[a.ID] {
+			readCount++
+		} else if nextArticleID == nil {
+			nextArticleID = &articles[i].ID
+		}
+	}
+	percent := 0
+	if published > 0 {
+		percent = readCount * 100 / published
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"series":   series,
+		"author":   author.Username,
+		"articles": entries,
+		"progress": gin.H{"read": readCount, "total": published, "percent": percent, "nextArticleId": nextArticleID},
+	})
+}
+
+// AddSeriesArticle 把作者的一篇文章加入系列，默认放在最后，仅作者或管理员可操作。
+// @Summary 添加系列文章
+// @Tags 系列
+// @Accept json
+// @Param id path int true "系列ID"
+// @Param articleId body int true "文章ID，须与系列同一作者，且不属于其他系列"
+// @Param position body int false "插入的位置，从 1 开始；默认放在最后"
+// @Router /api/series/{id}/articles [post]
+func AddSeriesArticle(ctx *gin.Context) {
+	series, _, ok := findOwnedSeries(ctx)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		ArticleID uint `json:"articleId" binding:"required"`
+		Position  int  `json:"position" binding:"min=0"`
+	}
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var article artice.Article
+	if err := global.Db.Select("id", "author_id").First(&article, input.ArticleID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	if article.AuthorID != series.AuthorID {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "only the series author's own articles can be added"})
+		return
+	}
+
+	var ids []uint
+	err := global.Db.Transaction(func(tx *gorm.DB) error {
+		if err := lockSeries(tx, series.ID); err != nil {
+			return err
+		}
+		var count int64
+		if err := tx.Model(&artice.SeriesArticle{}).Where("article_id = ?", article.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return errArticleInSeries
+		}
+
+		var err error
+		if ids, err = seriesArticleIDs(tx, series.ID); err != nil {
+			return err
+		}
+		index := len(ids)
+		if input.Position > 0 && input.Position <= len(ids) {
+			index = input.Position - 1
+		}
+		ids = append(ids[:index], append([]uint{article.ID}, ids[index:]...)...)
+
+		if err := tx.Create(&artice.SeriesArticle{SeriesID: series.ID, ArticleID: article.ID, Position: index + 1}).Error; err != nil {
+			return err
+		}
+		return renumberSeries(tx, series.ID, ids)
+	})
+	if errors.Is(err, errArticleInSeries) {
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"articleIds": ids})
+}
+
+// RemoveSeriesArticle 把文章移出系列，文章本身保留，仅作者或管理员可操作。
+// @Summary 移出系列文章
+// @Tags 系列
+// @Param id path int true "系列ID"
+// @Param articleId path int true "文章ID"
+// @Router /api/series/{id}/articles/{articleId} [delete]
+func RemoveSeriesArticle(ctx *gin.Context) {
+	series, _, ok := findOwnedSeries(ctx)
+	if !ok {
+		return
+	}
+
+	var ids []uint
+	err := global.Db.Transaction(func(tx *gorm.DB) error {
+		if err := lockSeries(tx, series.ID); err != nil {
+			return err
+		}
+		result := tx.Where("series_id = ? AND article_id = ?", series.ID, ctx.Param("articleId")).
+			Delete(&artice.SeriesArticle{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errSeriesArticleMiss
+		}
+
+		var err error
+		if ids, err = seriesArticleIDs(tx, series.ID); err != nil {
+			return err
+		}
+		return renumberSeries(tx, series.ID, ids)
+	})
+	if errors.Is(err, errSeriesArticleMiss) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"articleIds": ids})
+}
+
+// MoveSeriesArticle 把系列中的一篇文章移动到指定位置，仅作者或管理员可操作。
+// @Summary 移动系列文章
+// @Tags 系列
+// @Accept json
+// @Param id path int true "系列ID"
+// @Param articleId path int true "文章ID"
+// @Param position body int true "新的位置，从 1 开始；超出范围时移到最后"
+// @Router /api/series/{id}/articles/{articleId} [put]
+func MoveSeriesArticle(ctx *gin.Context) {
+	series, _, ok := findOwnedSeries(ctx)
+	if !ok {
+		return
+	}
+
+	articleID, err := strconv.ParseUint(ctx.Param("articleId"), 10, 64)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid articleId"})
+		return
+	}
+	var input struct {
+		Position int `json:"position" binding:"required,min=1"`
+	}
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var ids []uint
+	err = global.Db.Transaction(func(tx *gorm.DB) error {
+		if err := lockSeries(tx, series.ID); err != nil {
+			return err
+		}
+		current, err := seriesArticleIDs(tx, series.ID)
+		if err != nil {
+			return err
+		}
+
+		ids = make([]uint, 0, len(current))
+		for _, id := range current {
+			if id != uint(articleID) {
+				ids = append(ids, id)
+			}
+		}
+		if len(ids) == len(current) {
+			return errSeriesArticleMiss
+		}
+		index := min(input.Position-1, len(ids))
+		ids = append(ids[:index], append([]uint{uint(articleID)}, ids[index:]...)...)
+		return renumberSeries(tx, series.ID, ids)
+	})
+	if errors.Is(err, errSeriesArticleMiss) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"articleIds": ids})
+}
+
+// ReorderSeries 按给定的顺序重排系列中的所有文章，仅作者或管理员可操作。
+// @Summary 重排系列文章
+// @Tags 系列
+// @Accept json
+// @Param id path int true "系列ID"
+// @Param articleIds body []int true "系列中全部文章的ID，按新的顺序排列"
+// @Router /api/series/{id}/articles [put]
+func ReorderSeries(ctx *gin.Context) {
+	series, _, ok := findOwnedSeries(ctx)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		ArticleIDs []uint `json:"articleIds" binding:"required"`
+	}
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := global.Db.Transaction(func(tx *gorm.DB) error {
+		if err := lockSeries(tx, series.ID); err != nil {
+			return err
+		}
+		current, err := seriesArticleIDs(tx, series.ID)
+		if err != nil {
+			return err
+		}
+
+		// 新顺序必须恰好包含系列中的每篇文章各一次
+		if len(current) != len(input.ArticleIDs) {
+			return errSeriesOrder
+		}
+		remaining := make(map[uint]bool, len(current))
+		for _, id := range current {
+			remaining[id] = true
+		}
+		for _, id := range input.ArticleIDs {
+			if !remaining[id] {
+				return errSeriesOrder
+			}
+			delete(remaining, id)
+		}
+		return renumberSeries(tx, series.ID, input.ArticleIDs)
+	})
+	if errors.Is(err, errSeriesOrder) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"articleIds": input.ArticleIDs})
+}
@@ -0,0 +1,253 @@
+package controllers
+
+import (
+	"errors"
+	"exchangeapp/global"
+	"exchangeapp/middlewares"
+	"exchangeapp/models/user"
+	"exchangeapp/rsp"
+	"exchangeapp/utils"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// issueSession 为用户签发访问令牌和刷新令牌。family 为空时开始新的令牌族（新的登录），否则为刷新时的轮换
+func issueSession(u user.User, family string) (gin.H, error) {
+	if family == "" {
+		var err error
+		if family, err = utils.NewTokenFamily(); err != nil {
+			return nil, err
+		}
+	}
+
+	accessToken, claims, err := utils.GenerateJWT(u)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, hash, err := utils.GenerateRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := global.Db.Create(&user.RefreshToken{
+		UserID:          u.ID,
+		TokenHash:       hash,
+		Family:          family,
+		AccessJTI:       claims.JTI,
+		AccessExpiresAt: claims.ExpiresAt,
+		ExpiresAt:       time.Now().Add(utils.RefreshTokenTTL),
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	return gin.H{
+		"token":        accessToken, // 兼容旧客户端，与 accessToken 相同
+		"accessToken":  accessToken,
+		"refreshToken": refreshToken,
+		"tokenType":    "Bearer",
+		"expiresIn":    int(utils.AccessTokenTTL.Seconds()),
+	}, nil
+}
+
+// revokeTokens 吊销查询到的刷新令牌，并把与其一起签发、尚未过期的访问令牌加入黑名单
+func revokeTokens(query *gorm.DB) error {
+	now := time.Now()
+	var tokens []user.RefreshToken
+	if err := query.Where("revoked_at IS NULL OR access_expires_at > ?", now).Find(&tokens).Error; err != nil {
+		return err
+	}
+
+	var ids []uint
+	for _, t := range tokens {
+		if t.RevokedAt == nil {
+			ids = append(ids, t.ID)
+		}
+		if t.AccessJTI != "" {
+			if err := utils.RevokeAccessToken(t.AccessJTI, t.AccessExpiresAt); err != nil {
+				return err
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	return global.Db.Model(&user.RefreshToken{}).Where("id IN ?", ids).Update("revoked_at", now).Error
+}
+
+// revokeFamily 吊销一次登录轮换出的所有令牌
+func revokeFamily(family string) error {
+	return revokeTokens(global.Db.Where("family = ?", family))
+}
+
+// revokeUserSessions 吊销用户所有登录的令牌，用于全部登出和封禁
+func revokeUserSessions(userID uint) error {
+	return revokeTokens(global.Db.Where("user_id = ?", userID))
+}
+
+// RefreshToken 用刷新令牌换取新的访问令牌和刷新令牌，旧的刷新令牌随即失效。
+// 已失效的刷新令牌再次被使用时视为令牌泄露，吊销该次登录的所有令牌。
+// @Summary 刷新令牌
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param refreshToken body string true "刷新令牌"
+// @Success 200 {string} string "返回新的访问令牌和刷新令牌"
+// @Failure 500 {string} string "刷新令牌无效、已过期或被重复使用"
+// @Router /api/auth/refresh [post]
+func RefreshToken(ctx *gin.Context) {
+	var input struct {
+		RefreshToken string `json:"refreshToken" binding:"required"`
+	}
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		panic(rsp.NewErrorResponse(30001, err.Error(), nil))
+	}
+
+	var stored user.RefreshToken
+	err := global.Db.Where("token_hash = ?", utils.HashRefreshToken(input.RefreshToken)).First(&stored).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		panic(rsp.NewErrorResponse(50004, "refresh token not found", nil))
+	} else if err != nil {
+		panic(rsp.NewErrorResponse(20002, err.Error(), nil))
+	}
+
+	if stored.RevokedAt != nil {
+		if err := revokeFamily(stored.Family); err != nil {
+			panic(rsp.NewErrorResponse(20002, err.Error(), nil))
+		}
+		panic(rsp.NewErrorResponse(50005, "refresh token reused", nil))
+	}
+	if time.Now().After(stored.ExpiresAt) {
+		panic(rsp.NewErrorResponse(50004, "refresh token expired", nil))
+	}
+
+	var u user.User
+	if err := global.Db.First(&u, stored.UserID).Error; err != nil {
+		panic(rsp.NewErrorResponse(50004, err.Error(), nil))
+	}
+	if u.IsBanned {
+		panic(rsp.NewErrorResponse(10004, "user is banned", nil))
+	}
+
+	// 条件更新保证并发刷新时只有一个请求成功，其余按重复使用处理
+	result := global.Db.Model(&user.RefreshToken{}).Where("id = ? AND revoked_at IS NULL", stored.ID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		panic(rsp.NewErrorResponse(20002, result.Error.Error(), nil))
+	}
+	if result.RowsAffected == 0 {
+		if err := revokeFamily(stored.Family); err != nil {
+			panic(rsp.NewErrorResponse(20002, err.Error(), nil))
+		}
+		panic(rsp.NewErrorResponse(50005, "refresh token reused", nil))
+	}
+
+	tokens, err := issueSession(u, stored.Family)
+	if err != nil {
+		panic(rsp.NewErrorResponse(50001, err.Error(), nil))
+	}
+
+	ctx.JSON(http.StatusOK, tokens)
+}
+
+// Logout 登出：当前访问令牌立即失效，并吊销其所属登录的刷新令牌；all 为 true 时吊销该用户所有登录的令牌。
+// @Summary 登出
+// @Tags Auth
+// @Accept json
+// @Param all body bool false "是否登出所有设备"
+// @Router /api/auth/logout [post]
+func Logout(ctx *gin.Context) {
+	u, err := middlewares.CurrentUser(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var input struct {
+		All bool `json:"all"`
+	}
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&input); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	jti := ctx.GetString("jti")
+	if err := utils.RevokeAccessToken(jti, ctx.GetTime("tokenExpiresAt")); err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if input.All {
+		err = revokeUserSessions(u.ID)
+	} else {
+		// 通过随访问令牌一起签发的刷新令牌找到本次登录
+		var stored user.RefreshToken
+		err = global.Db.Where("user_id = ? AND access_jti = ?", u.ID, jti).First(&stored).Error
+		if err == nil {
+			err = revokeFamily(stored.Family)
+		} else if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = nil
+		}
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Successfully logged out"})
+}
+
+// RevokeTokens 吊销指定的访问令牌（jti）或指定用户的所有令牌，用于处理泄露的令牌，仅管理员可用。
+// @Summary 吊销令牌
+// @Tags Auth
+// @Accept json
+// @Param jti body string false "访问令牌的 jti，同时吊销其所属登录的刷新令牌"
+// @Param userId body int false "用户ID，吊销该用户所有登录的令牌"
+// @Router /api/auth/revoke [post]
+func RevokeTokens(ctx *gin.Context) {
+	if _, ok := requireAdmin(ctx); !ok {
+		return
+	}
+
+	var input struct {
+		JTI    string `json:"jti"`
+		UserID uint   `json:"userId"`
+	}
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.JTI == "" && input.UserID == 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "jti or userId is required"})
+		return
+	}
+
+	if input.JTI != "" {
+		// 不知道令牌的过期时间时按最长有效期加入黑名单
+		if err := utils.RevokeAccessToken(input.JTI, time.Now().Add(utils.AccessTokenTTL)); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		var stored user.RefreshToken
+		err := global.Db.Where("access_jti = ?", input.JTI).First(&stored).Error
+		if err == nil {
+			err = revokeFamily(stored.Family)
+		}
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if input.UserID != 0 {
+		if err := revokeUserSessions(input.UserID); err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Successfully revoked the tokens"})
+}
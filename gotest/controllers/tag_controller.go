@@ -0,0 +1,219 @@
+package controllers
+
+import (
+	"errors"
+	"exchangeapp/global"
+	"exchangeapp/middlewares"
+	"exchangeapp/models/artice"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 标签相关限制
+const (
+	maxTagLength      = 50 // 标签名的最大长度
+	maxTagsPerArticle = 10 // 每篇文章最多的标签数
+	defaultTagLimit   = 10 // 标签自动补全默认返回的数量
+)
+
+// tagCount 标签及其关联的文章数
+type tagCount struct {
+	ID    uint   `json:"id"`
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+// GetTags 获取标签及文章数，可按前缀过滤用于自动补全。
+// @Summary 获取标签
+// @Description 按文章数从多到少排序；传入 prefix 时只返回以其开头的标签。
+// @Tags 文章操作
+// @Param prefix query string false "标签前缀"
+// @Param limit query int false "返回数量，默认 10，最大 100"
+// @Produce json
+// @Router /api/tags [get]
+func GetTags(ctx *gin.Context) {
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", strconv.Itoa(defaultTagLimit)))
+	if err != nil || limit <= 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+	limit = min(limit, maxArticlePageSize)
+
+	query := global.Db.Table("tags").
+		Select("tags.id, tags.name, COUNT(articles.id) AS count").
+		Joins("LEFT JOIN article_tags ON article_tags.tag_id = tags.id").
+		Joins("LEFT JOIN articles ON articles.id = article_tags.article_id AND articles.deleted_at IS NULL").
+		Group("tags.id, tags.name")
+
+	if prefix := normalizeTagName(ctx.Query("prefix")); prefix != "" {
+		// 转义 LIKE 通配符，避免前缀中的 % 和 _ 被当作通配符
+		escaped := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(prefix)
+		query = query.Where("tags.name LIKE ?", escaped+"%")
+	}
+
+	var tags []tagCount
+	if err := query.Order("count DESC, tags.name ASC").Limit(limit).Scan(&tags).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, tags)
+}
+
+// GetCategories 获取完整的分类树。
+// @Summary 获取分类树
+// @Tags 文章操作
+// @Produce json
+// @Router /api/categories [get]
+func GetCategories(ctx *gin.Context) {
+	var categories []artice.Category
+	if err := global.Db.Order("id").Find(&categories).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, buildCategoryTree(categories, nil))
+}
+
+// CreateCategory 创建分类，仅管理员可操作。
+// @Summary 创建分类
+// @Tags 文章操作
+// @Accept json
+// @Produce json
+// @Router /api/categories [post]
+func CreateCategory(ctx *gin.Context) {
+	u, err := middlewares.CurrentUser(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if !u.IsAdmin() {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "only admins can create categories"})
+		return
+	}
+
+	var category artice.Category
+	if err := ctx.ShouldBindJSON(&category); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	category.ID = 0
+	category.Children = nil
+
+	if category.ParentID != nil {
+		var parent artice.Category
+		if err := global.Db.First(&parent, *category.ParentID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": "parent category not found"})
+			} else {
+				ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			}
+			return
+		}
+	}
+
+	if err := global.Db.Create(&category).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, category)
+}
+
+// buildCategoryTree 把平铺的分类组装成以 parentID 为根的树
+func buildCategoryTree(categories []artice.Category, parentID *uint) []artice.Category {
+	tree := []artice.Category{}
+	for _, c := range categories {
+		if (parentID == nil && c.ParentID == nil) || (parentID != nil && c.ParentID != nil && *c.ParentID == *parentID) {
+			c.Children = buildCategoryTree(categories, &c.ID)
+			tree = append(tree, c)
+		}
+	}
+	return tree
+}
+
+// categoryWithDescendants 返回分类本身及其所有下级分类的 ID
+func categoryWithDescendants(tx *gorm.DB, id uint) ([]uint, error) {
+	var categories []artice.Category
+	if err := tx.Select("id", "parent_id").Find(&categories).Error; err != nil {
+		return nil, err
+	}
+
+	children := make(map[uint][]uint)
+	for _, c := range categories {
+		if c.ParentID != nil {
+			children[*c.ParentID] = append(children[*c.ParentID], c.ID)
+		}
+	}
+
+	ids := []uint{id}
+	for i := 0; i < len(ids); i++ {
+		ids = append(ids, children[ids[i]]...)
+	}
+	return ids, nil
+}
+
+// normalizeTagName 统一标签名：去除首尾空白并转为小写
+func normalizeTagName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// errCategoryNotFound 文章引用的分类不存在
+var errCategoryNotFound = errors.New("category not found")
+
+// normalizeTags 规范化并去重请求中的标签名，校验长度和数量
+func normalizeTags(tags []artice.Tag) ([]string, error) {
+	seen := make(map[string]bool, len(tags))
+	names := make([]string, 0, len(tags))
+
+	for _, t := range tags {
+		name := normalizeTagName(t.Name)
+		if name == "" || seen[name] {
+			continue
+		}
+		if utf8.RuneCountInString(name) > maxTagLength {
+			return nil, fmt.Errorf("tag %q is longer than %d characters", name, maxTagLength)
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+
+	if len(names) > maxTagsPerArticle {
+		return nil, fmt.Errorf("an article can have at most %d tags", maxTagsPerArticle)
+	}
+	return names, nil
+}
+
+// resolveTags 按名称查找标签，不存在时创建
+func resolveTags(tx *gorm.DB, names []string) ([]artice.Tag, error) {
+	tags := make([]artice.Tag, 0, len(names))
+	for _, name := range names {
+		tag := artice.Tag{Name: name}
+		if err := tx.Where("name = ?", name).FirstOrCreate(&tag).Error; err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// validateCategory 校验文章引用的分类是否存在
+func validateCategory(tx *gorm.DB, categoryID *uint) error {
+	if categoryID == nil {
+		return nil
+	}
+	var category artice.Category
+	if err := tx.First(&category, *categoryID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errCategoryNotFound
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"exchangeapp/global"
+	"exchangeapp/models/artice"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 浏览统计在 Redis 中的键名、按天统计键的过期时间和日期格式
+const (
+	viewDaysKey   = "views:days" // 尚需同步到 MySQL 的日期（YYYYMMDD）
+	viewDayTTL    = 3 * 24 * time.Hour
+	viewDayLayout = "20060102"
+)
+
+// viewCountKey 生成某天各文章浏览量的哈希键名，字段为文章ID
+func viewCountKey(day string) string {
+	return "views:pv:" + day
+}
+
+// dailyViewersKey 生成文章某天访客的 HyperLogLog 键名
+func dailyViewersKey(articleID uint, day string) string {
+	return fmt.Sprintf("article:%d:uv:%s", articleID, day)
+}
+
+// viewersKey 生成文章全部访客的 HyperLogLog 键名
+func viewersKey(articleID uint) string {
+	return fmt.Sprintf("article:%d:viewers", articleID)
+}
+
+// viewerIdentity 生成访客标识：登录用户使用用户ID，匿名访客使用 IP 和 User-Agent 的摘要
+func viewerIdentity(ctx *gin.Context, userID uint) string {
+	if userID != 0 {
+		return fmt.Sprintf("u:%d", userID)
+	}
+	sum := sha1.Sum([]byte(ctx.ClientIP() + "|" + ctx.Request.UserAgent()))
+	return "a:" + hex.EncodeToString(sum[:])
+}
+
+// recordView 记录一次浏览：累加当天浏览量，并把访客加入当天和全部访客的 HyperLogLog。
+// 返回该访客是否为当天的新访客。
+func recordView(articleID uint, viewer string) (bool, error) {
+	day := time.Now().Format(viewDayLayout)
+	dailyKey := dailyViewersKey(articleID, day)
+
+	pipe := global.RedisDB.TxPipeline()
+	added := pipe.PFAdd(dailyKey, viewer)
+	pipe.Expire(dailyKey, viewDayTTL)
+	pipe.PFAdd(viewersKey(articleID), viewer)
+	pipe.HIncrBy(viewCountKey(day), fmt.Sprint(articleID), 1)
+	pipe.Expire(viewCountKey(day), viewDayTTL)
+	pipe.SAdd(viewDaysKey, day)
+	if _, err := pipe.Exec(); err != nil {
+		return false, err
+	}
+	return added.Val() == 1, nil
+}
+
+// GetArticleViews 获取文章每天的浏览量和独立访客数，仅作者本人或管理员可查看。
+// @Summary 获取文章浏览统计
+// @Description 默认返回最近 30 天；数据由后台任务定期从 Redis 同步，可能略有延迟。
+// @Tags 文章操作
+// @Param id path string true "文章ID"
+// @Param from query string false "起始日期"
+// @Param to query string false "结束日期"
+// @Produce json
+// @Router /api/articles/{id}/views [get]
+func GetArticleViews(ctx *gin.Context) {
+	article, _, ok := findOwnedArticle(ctx)
+	if !ok {
+		return
+	}
+
+	to := time.Now()
+	if value := ctx.Query("to"); value != "" {
+		t, err := parseQueryTime(value)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		to = t
+	}
+	from := to.AddDate(0, 0, -30)
+	if value := ctx.Query("from"); value != "" {
+		t, err := parseQueryTime(value)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		from = t
+	}
+
+	days := []artice.ArticleViewDaily{}
+	if err := global.Db.Where("article_id = ? AND date BETWEEN ? AND ?", article.ID,
+		from.Format(time.DateOnly), to.Format(time.DateOnly)).
+		Order("date asc").Find(&days).Error; err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"views":       article.Views,
+		"uniqueViews": article.UniqueViews,
+		"days":        days,
+	})
+}
@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"exchangeapp/global"
+	"exchangeapp/models/artice"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// StartViewSync 启动后台任务，定期把 Redis 中的浏览统计同步到 MySQL
+func StartViewSync(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := SyncViews(); err != nil {
+				log.Printf("浏览统计同步失败: %v", err)
+			}
+		}
+	}()
+}
+
+// SyncViews 把各日期的浏览量和独立访客数写入按天的统计表，并刷新文章的总浏览量和独立访客数。
+// Redis 中保存的是各天的累计值，同步是幂等的，失败后下次重试即可。
+func SyncViews() error {
+	days, err := global.RedisDB.SMembers(viewDaysKey).Result()
+	if err != nil {
+		return err
+	}
+
+	today := time.Now().Format(viewDayLayout)
+	touched := make(map[uint]bool)
+	for _, day := range days {
+		ids, err := syncViewDay(day)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			touched[id] = true
+		}
+
+		// 已经过去的日期不会再有新的浏览，同步完成后不再处理
+		if day < today {
+			if err := global.RedisDB.SRem(viewDaysKey, day).Err(); err != nil {
+				return err
+			}
+		}
+	}
+
+	for id := range touched {
+		if err := syncArticleViewTotals(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncViewDay 同步某一天各文章的浏览统计，返回涉及的文章ID
+func syncViewDay(day string) ([]uint, error) {
+	date, err := time.ParseInLocation(viewDayLayout, day, time.Local)
+	if err != nil {
+		return nil, err
+	}
+
+	counts, err := global.RedisDB.HGetAll(viewCountKey(day)).Result()
+	if err != nil || len(counts) == 0 {
+		return nil, err
+	}
+
+	rows := make([]artice.ArticleViewDaily, 0, len(counts))
+	ids := make([]uint, 0, len(counts))
+	pipe := global.RedisDB.Pipeline()
+	for field, value := range counts {
+		id, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			continue
+		}
+		views, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			continue
+		}
+		rows = append(rows, artice.ArticleViewDaily{ArticleID: uint(id), Date: date, Views: views})
+		ids = append(ids, uint(id))
+	}
+	uniques := make([]*redis.IntCmd, len(rows))
+	for i, row := range rows {
+		uniques[i] = pipe.PFCount(dailyViewersKey(row.ArticleID, day))
+	}
+	if _, err := pipe.Exec(); err != nil {
+		return nil, err
+	}
+	for i := range rows {
+		rows[i].UniqueViews = uniques[i].Val()
+	}
+
+	err = global.Db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "article_id"}, {Name: "date"}},
+		DoUpdates: clause.AssignmentColumns([]string{"views", "unique_views"}),
+	}).CreateInBatches(rows, 500).Error
+	return ids, err
+}
+
+// syncArticleViewTotals 刷新文章的总浏览量和独立访客数。
+// 总浏览量取各天之和；独立访客数取全部访客的 HyperLogLog，Redis 数据丢失时保留原值。
+func syncArticleViewTotals(articleID uint) error {
+	uniques, err := global.RedisDB.PFCount(viewersKey(articleID)).Result()
+	if err != nil {
+		return err
+	}
+
+	return global.Db.Model(&artice.Article{}).Where("id = ?", articleID).UpdateColumns(map[string]interface{}{
+		"views": global.Db.Model(&artice.ArticleViewDaily{}).Select("COALESCE(SUM(views), 0)").
+			Where("article_id = ?", articleID),
+		"unique_views": gorm.Expr("GREATEST(unique_views, ?)", uniques),
+	}).Error
+}
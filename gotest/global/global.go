@@ -10,6 +10,5 @@ var (
 	// Db 是 GORM 的数据库连接实例，能够用来与数据库进行交互
 	Db *gorm.DB
 	// RedisDB 是 Redis 的客户端连接实例，用于与 Redis 进行交互
-	RedisDB   *redis.Client
-	JwtSecret = []byte("key") // 可以替换成你自己的秘钥
+	RedisDB *redis.Client
 )
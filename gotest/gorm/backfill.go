@@ -0,0 +1,55 @@
+package gorm
+
+import (
+	"exchangeapp/global"
+	"exchangeapp/models/user"
+	"fmt"
+	"log"
+
+	"gorm.io/gorm"
+)
+
+// backfillPhones 把已有用户的手机号改写为 E.164 格式，与登录时规范化后的查询保持一致。
+// 已是规范格式的记录不做改动，可以重复执行。无法规范化的号码，以及规范化后与其他用户重复的号码
+// 永远无法用于登录，还会使该用户后续的保存失败，因此清空并记录日志，由用户重新绑定
+func backfillPhones() error {
+	var users []user.User
+	return global.Db.Select("id", "phone").Where("phone IS NOT NULL").
+		FindInBatches(&users, 500, func(tx *gorm.DB, batch int) error {
+			for _, u := range users {
+				phone, err := user.NormalizePhone(*u.Phone)
+				if err == nil && phone == *u.Phone {
+					continue
+				}
+
+				var value interface{}
+				if err != nil {
+					log.Printf("用户 %d 的手机号 %q 无法规范化，已清空", u.ID, *u.Phone)
+				} else {
+					var count int64
+					if err := global.Db.Model(&user.User{}).Where("phone = ? AND id <> ?", phone, u.ID).Count(&count).Error; err != nil {
+						return err
+					}
+					if count > 0 {
+						log.Printf("用户 %d 的手机号 %q 规范化后与其他用户重复，已清空", u.ID, *u.Phone)
+					} else {
+						value = phone
+					}
+				}
+
+				// UpdateColumn 跳过 BeforeSave，直接写入规范化后的值
+				if err := global.Db.Model(&user.User{}).Where("id = ?", u.ID).UpdateColumn("phone", value).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+}
+
+// runBackfills 在自动迁移之后执行数据回填
+func runBackfills() {
+	if err := backfillPhones(); err != nil {
+		log.Fatalf("手机号回填失败: %v", err)
+	}
+	fmt.Println("手机号回填完成!")
+}
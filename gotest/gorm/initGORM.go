@@ -2,6 +2,7 @@ package gorm
 
 import (
 	"exchangeapp/global"
+	"exchangeapp/models/artice"
 	"exchangeapp/models/user"
 	"fmt"
 	"log"
@@ -11,6 +12,29 @@ import (
 func InitGORM() {
 	entities := []interface{}{
 		&user.User{},
+		&user.Follow{},
+		&user.RefreshToken{},
+		&artice.Tag{},
+		&artice.Category{},
+		&artice.Article{},
+		&artice.Comment{},
+		&artice.ArticleLike{},
+		&artice.ArticleRevision{},
+		&artice.ArticleViewDaily{},
+		&artice.ReadingList{},
+		&artice.Bookmark{},
+		&artice.Series{},
+		&artice.SeriesArticle{},
+		&artice.SeriesRead{},
+		&artice.Attachment{},
+		&artice.SensitiveWord{},
+		&artice.ModerationItem{},
+		&artice.Report{},
+		&artice.Notification{},
+		&artice.ExchangeRate{},
+		&artice.Basket{},
+		&artice.BasketComponent{},
+		&artice.BasketValue{},
 		// 更多结构体
 	}
 
@@ -32,5 +56,7 @@ func InitGORM() {
 		log.Fatalf("以下结构体迁移失败: %v", failedEntities)
 	}
 
+	runBackfills()
+
 	fmt.Println("数据库连接和所有结构体自动迁移成功!")
 }
@@ -4,9 +4,13 @@ import (
 	"context"
 	"errors"
 	"exchangeapp/config"
+	"exchangeapp/controllers"
 	_ "exchangeapp/docs" // main 文件中导入 docs 包
 	"exchangeapp/gorm"
 	"exchangeapp/router"
+	"exchangeapp/search"
+	"exchangeapp/storage"
+	"exchangeapp/utils"
 	"exchangeapp/websorket"
 	"fmt"
 	"log"
@@ -26,9 +30,35 @@ func main() {
 		config.InitConfig()
 		// 初始化 GORM 数据库连接
 		gorm.InitGORM()
+		// 初始化全文检索
+		search.InitSearch()
+		// 初始化文件存储
+		storage.InitStorage()
+		// 加载 JWT 签名密钥
+		utils.InitJWT()
 		fmt.Println("加载成功配置环境")
 
 	})
+	// 命令行子命令：go run . rebuild-rankings 从数据库重建排行榜后退出
+	if len(os.Args) > 1 && os.Args[1] == "rebuild-rankings" {
+		if err := controllers.RebuildRankings(); err != nil {
+			log.Fatalf("排行榜重建失败: %v", err)
+		}
+		log.Println("排行榜重建完成")
+		return
+	}
+
+	// 启动后台任务：定期把 Redis 中的点赞变更同步到 MySQL
+	controllers.StartLikeSync(30 * time.Second)
+	// 启动后台任务：定期把 Redis 中的浏览统计同步到 MySQL
+	controllers.StartViewSync(time.Minute)
+	// 启动后台任务：每天重建一次排行榜，刷新热度的基准时间
+	controllers.StartRankingRebuild(24 * time.Hour)
+	// 加载敏感词库，并启动后台任务：其他实例修改词库后重新加载
+	controllers.StartSensitiveWordSync(config.AppConfig.Sensitive.SyncInterval)
+	// 启动后台任务：在定时文章的发布时间到达时自动发布
+	controllers.StartPublishScheduler()
+
 	// 设置路由
 	r := router.SetupRouter()
 
@@ -0,0 +1,106 @@
+package markdown
+
+import (
+	"bytes"
+	"html"
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// 阅读速度：英文按单词计，中日韩文字按字计
+const (
+	wordsPerMinute = 200
+	cjkPerMinute   = 400
+)
+
+// renderer 支持 GFM（表格、删除线、任务列表、自动链接）的 Markdown 渲染器。
+// 未启用 html.WithUnsafe，源文本中的原始 HTML 不会被输出。
+var renderer = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+// policy 渲染结果的白名单过滤，去除脚本、事件属性和 javascript: 链接等
+var policy = func() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowAttrs("class").Matching(bluemonday.SpaceSeparatedTokens).OnElements("code")
+	p.AllowAttrs("type", "checked", "disabled").OnElements("input")
+	p.RequireNoFollowOnLinks(true)
+	p.AddTargetBlankToFullyQualifiedLinks(true)
+	return p
+}()
+
+// strip 去除全部标签，用于生成纯文本
+var strip = bluemonday.StrictPolicy()
+
+// Document Markdown 源文本渲染后的结果
+type Document struct {
+	HTML        string // 过滤后的安全 HTML
+	Text        string // 纯文本，空白已合并
+	WordCount   int    // 字数
+	ReadingTime int    // 预计阅读时间（分钟）
+}
+
+// Render 把 Markdown 渲染为过滤后的 HTML，并统计纯文本字数和阅读时间
+func Render(source string) (Document, error) {
+	var buf bytes.Buffer
+	if err := renderer.Convert([]byte(source), &buf); err != nil {
+		return Document{}, err
+	}
+
+	doc := Document{HTML: policy.Sanitize(buf.String())}
+	// 块级元素之间补空格，避免相邻段落的文字粘连
+	spaced := strings.NewReplacer("</p>", "</p> ", "</li>", "</li> ", "<br>", " ", "</h", " </h").Replace(buf.String())
+	doc.Text = strings.Join(strings.Fields(html.UnescapeString(strip.Sanitize(spaced))), " ")
+
+	words, cjk := countWords(doc.Text)
+	doc.WordCount = words + cjk
+	if doc.WordCount > 0 {
+		minutes := float64(words)/wordsPerMinute + float64(cjk)/cjkPerMinute
+		doc.ReadingTime = max(1, int(math.Ceil(minutes)))
+	}
+	return doc, nil
+}
+
+// Preview 截取纯文本的前 limit 个字符作为摘要，尽量在单词边界截断
+func Preview(text string, limit int) string {
+	if utf8.RuneCountInString(text) <= limit {
+		return text
+	}
+
+	runes := []rune(text)
+	cut := limit
+	// 英文单词不从中间截断；回退过多时（长单词或中文）直接按字符截断
+	for i := limit; i > limit*3/4; i-- {
+		if unicode.IsSpace(runes[i]) {
+			cut = i
+			break
+		}
+	}
+	return strings.TrimSpace(string(runes[:cut])) + "…"
+}
+
+// countWords 分别统计英文等以空白分词的单词数和中日韩文字数
+func countWords(text string) (words, cjk int) {
+	inWord := false
+	for _, r := range text {
+		switch {
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+			cjk++
+			inWord = false
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if !inWord {
+				words++
+			}
+			inWord = true
+		case r == '\'' || r == '-':
+			// 单词内的撇号和连字符不拆分单词
+		default:
+			inWord = false
+		}
+	}
+	return words, cjk
+}
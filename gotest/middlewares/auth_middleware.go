@@ -1,39 +1,76 @@
-package middlewares
-
-import (
-	"exchangeapp/utils" // 导入 utils 包，处理 JWT 解析
-	"net/http"          // 导入 net/http 包，用于 HTTP 状态码
-
-	"github.com/gin-gonic/gin" // 导入 Gin 框架
-)
-
-// AuthMiddleWare 返回一个 Gin 中间件，用于处理身份验证
-func AuthMiddleWare() gin.HandlerFunc {
-	return func(ctx *gin.Context) {
-		// 从请求头中获取 Authorization 字段
-		token := ctx.GetHeader("Authorization")
-
-		// 如果没有提供 Authorization Header，则返回 401 未授权错误
-		if token == "" {
-			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Missing Authorization Header"})
-			ctx.Abort() // 中止当前请求的处理
-			return
-		}
-
-		// 解析 JWT，获取用户名和验证 token 是否有效
-		username, err := utils.ParseJWT(token)
-
-		// 如果解析失败，返回 401 未授权错误
-		if err != nil {
-			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
-			ctx.Abort() // 中止当前请求的处理
-			return
-		}
-
-		// 如果 token 有效，将用户名存入上下文中
-		ctx.Set("username", username)
-
-		// 继续处理请求
-		ctx.Next()
-	}
-}
+package middlewares
+
+import (
+	"exchangeapp/utils" // 导入 utils 包，处理 JWT 解析
+	"net/http"          // 导入 net/http 包，用于 HTTP 状态码
+
+	"github.com/gin-gonic/gin" // 导入 Gin 框架
+)
+
+// AuthMiddleWare 返回一个 Gin 中间件，用于处理身份验证
+func AuthMiddleWare() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		// 从请求头中获取 Authorization 字段，支持 "Bearer <token>" 和直接传入令牌两种形式
+		token := utils.StripBearer(ctx.GetHeader("Authorization"))
+
+		// 如果没有提供 Authorization Header，则返回 401 未授权错误
+		if token == "" {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Missing Authorization Header"})
+			ctx.Abort() // 中止当前请求的处理
+			return
+		}
+
+		if !authenticate(ctx, token) {
+			return
+		}
+
+		// 继续处理请求
+		ctx.Next()
+	}
+}
+
+// OptionalAuthMiddleWare 可选的身份验证：没有 Authorization Header 时按匿名访客继续处理，上下文中没有用户信息；
+// 提供了令牌时与 AuthMiddleWare 一样校验，无效或已吊销的令牌返回 401，而不是悄悄降级为匿名
+func OptionalAuthMiddleWare() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		token := utils.StripBearer(ctx.GetHeader("Authorization"))
+		if token != "" && !authenticate(ctx, token) {
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// authenticate 校验令牌并把用户信息写入上下文；校验失败时写入响应、中止请求并返回 false
+func authenticate(ctx *gin.Context, token string) bool {
+	// 解析 JWT，获取用户名和验证 token 是否有效
+	claims, err := utils.ParseJWT(token)
+
+	// 如果解析失败，返回 401 未授权错误
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		ctx.Abort() // 中止当前请求的处理
+		return false
+	}
+
+	// 检查 token 是否已被吊销；无法确认时拒绝请求，避免已吊销的 token 趁机生效
+	revoked, err := utils.IsAccessTokenRevoked(claims.JTI)
+	if err != nil {
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": "Unable to verify token"})
+		ctx.Abort()
+		return false
+	}
+	if revoked {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
+		ctx.Abort()
+		return false
+	}
+
+	// 如果 token 有效，将用户信息存入上下文中，由 CurrentUser 读取；jti 和过期时间在登出时使用
+	ctx.Set("userId", claims.UserID)
+	ctx.Set("username", claims.Username)
+	ctx.Set("level", claims.Level)
+	ctx.Set("jti", claims.JTI)
+	ctx.Set("tokenExpiresAt", claims.ExpiresAt)
+	return true
+}
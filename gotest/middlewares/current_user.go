@@ -0,0 +1,25 @@
+package middlewares
+
+import (
+	"errors"
+	"exchangeapp/models/user"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrNoUser 上下文中没有登录用户：路由没有经过 AuthMiddleWare，或经过 OptionalAuthMiddleWare 的匿名访客
+var ErrNoUser = errors.New("no authenticated user")
+
+// CurrentUser 根据 authenticate 写入上下文的令牌声明构造当前登录用户，不查询数据库，
+// 只有 ID、Username 和 Level 有值。封禁用户时会吊销其所有令牌，由中间件拒绝；
+// Level 为签发时的等级，等级变更在下次刷新令牌时生效，最长延迟 AccessTokenTTL
+func CurrentUser(ctx *gin.Context) (user.User, error) {
+	var u user.User
+	u.ID = ctx.GetUint("userId")
+	if u.ID == 0 {
+		return u, ErrNoUser
+	}
+	u.Username = ctx.GetString("username")
+	u.Level = ctx.GetInt("level")
+	return u, nil
+}
@@ -1,10 +1,117 @@
 package artice
 
-import "gorm.io/gorm"
+import (
+	"database/sql/driver"
+	"exchangeapp/models/user"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 文章状态
+const (
+	StatusDraft     = "draft"     // 草稿，仅作者可见
+	StatusScheduled = "scheduled" // 定时发布，到达 PublishAt 后由后台任务发布
+	StatusPublished = "published" // 已发布，所有人可见
+	StatusArchived  = "archived"  // 已归档，仅作者可见
+	StatusPending   = "pending"   // 命中敏感词待审核，仅作者和管理员可见，审核通过后发布
+	StatusHidden    = "hidden"    // 被多人举报后自动隐藏，仅作者和管理员可见，由管理员处理举报后恢复或移除
+)
+
+// 文章的可见范围。团队文章不进入订阅、搜索和排行榜等所有人共享的内容
+const (
+	VisibilityPublic = "public" // 所有人可见
+	VisibilityTeam   = "team"   // 仅所属团队的成员可见
+	VisibilityRoles  = "roles"  // 仅所属团队中担任 VisibleRoles 所列角色的成员可见
+)
 
 type Article struct {
 	gorm.Model
-	Title   string `binding:"required"`
-	Content string `binding:"required"`
-	Preview string `binding:"required"`
+	Title        string     `binding:"required"`
+	Content      string     `binding:"required"` // Markdown 源文本
+	Preview      string     // 纯文本摘要，未提供时由正文自动生成
+	AuthorID     uint       `gorm:"index"`                                      // 作者ID（user.User），取自 JWT 中的当前用户
+	Likes        int64      `gorm:"default:0;index"`                            // 点赞数，由后台任务从 Redis 同步，用于按点赞排序
+	CategoryID   *uint      `gorm:"index"`                                      // 所属分类
+	Tags         []Tag      `gorm:"many2many:article_tags;" binding:"-"`        // 标签，按名称匹配，不存在时自动创建
+	Status       string     `gorm:"type:varchar(20);default:'published';index"` // 文章状态：draft、scheduled、published、archived、pending、hidden
+	PublishAt    *time.Time `gorm:"index"`                                      // 发布时间；定时发布的文章在该时间自动发布
+	ContentHTML  string     `gorm:"type:longtext" binding:"-"`                  // 由 Content 渲染并过滤后的 HTML，随正文一起保存
+	WordCount    int        `binding:"-"`                                       // 正文字数
+	ReadingTime  int        `binding:"-"`                                       // 预计阅读时间（分钟）
+	Views        int64      `gorm:"default:0" binding:"-"`                      // 总浏览量，由后台任务从 Redis 同步
+	UniqueViews  int64      `gorm:"default:0" binding:"-"`                      // 独立访客数（近似值），由后台任务从 Redis 同步
+	TeamID       *uint      `gorm:"index"`                                      // 所属团队，为空表示个人文章
+	Visibility   string     `gorm:"type:varchar(20);default:'public';index"`    // 可见范围：public、team、roles
+	VisibleRoles RoleSet    `gorm:"type:varchar(100)"`                          // Visibility 为 roles 时可见的团队角色
+	LikedByMe    bool       `gorm:"-"`                                          // 当前用户是否已点赞，仅用于响应
+	Bookmarked   bool       `gorm:"-"`                                          // 当前用户是否已收藏到任一阅读列表，仅用于响应
+	Series       *SeriesNav `gorm:"-" json:",omitempty"`                        // 所属系列及前后篇，仅在获取单篇文章时返回
+}
+
+// IsPublished 判断文章是否已发布
+func (a *Article) IsPublished() bool {
+	return a.Status == StatusPublished
+}
+
+// IsPublic 判断文章是否已发布且所有人可见
+func (a *Article) IsPublic() bool {
+	return a.IsPublished() && (a.Visibility == "" || a.Visibility == VisibilityPublic)
+}
+
+// VisibleTo 判断文章对用户是否可见。作者和管理员总是可见；其他用户只能看到已发布的文章，
+// 团队文章还要求用户是团队成员并担任允许的角色。role 为用户在文章所属团队中的角色，不是成员时为空。
+func (a *Article) VisibleTo(u *user.User, role string) bool {
+	if a.AuthorID == u.ID || u.IsAdmin() {
+		return true
+	}
+	if !a.IsPublished() {
+		return false
+	}
+	switch a.Visibility {
+	case VisibilityTeam:
+		return role != ""
+	case VisibilityRoles:
+		return role != "" && a.VisibleRoles.Contains(role)
+	}
+	return true
+}
+
+// RoleSet 团队角色的集合，数据库中保存为逗号分隔的字符串，以便用 FIND_IN_SET 查询
+type RoleSet []string
+
+// Value 实现 driver.Valuer
+func (r RoleSet) Value() (driver.Value, error) {
+	return strings.Join(r, ","), nil
+}
+
+// Scan 实现 sql.Scanner
+func (r *RoleSet) Scan(value interface{}) error {
+	var s string
+	switch v := value.(type) {
+	case nil:
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	default:
+		return fmt.Errorf("cannot scan %T into RoleSet", value)
+	}
+	*r = nil
+	if s != "" {
+		*r = strings.Split(s, ",")
+	}
+	return nil
+}
+
+// Contains 判断集合中是否包含 role
+func (r RoleSet) Contains(role string) bool {
+	for _, v := range r {
+		if v == role {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,18 @@
+package artice
+
+import "time"
+
+// Attachment 上传的媒体文件，Key 为其在存储后端中的路径
+type Attachment struct {
+	ID          uint      `gorm:"primarykey" json:"id"`
+	UserID      uint      `gorm:"not null;index" json:"userId"` // 上传者
+	ArticleID   *uint     `gorm:"index" json:"articleId"`       // 关联的文章，为空表示尚未关联
+	Key         string    `gorm:"type:varchar(255);uniqueIndex" json:"-"`
+	Filename    string    `gorm:"type:varchar(255)" json:"filename"` // 上传时的原始文件名
+	ContentType string    `gorm:"type:varchar(100)" json:"contentType"`
+	Size        int64     `json:"size"`
+	Width       int       `json:"width"`
+	Height      int       `json:"height"`
+	CreatedAt   time.Time `json:"createdAt"`
+	URL         string    `gorm:"-" json:"url"` // 签名下载地址，仅用于响应
+}
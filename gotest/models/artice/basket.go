@@ -0,0 +1,33 @@
+package artice
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Basket 货币篮子（自定义指数）模型，例如以 CNY 计价的 USD/EUR/JPY 加权篮子
+type Basket struct {
+	gorm.Model
+	Name         string            `gorm:"type:varchar(100);not null;unique" json:"name" binding:"required"` // 篮子名称，唯一
+	BaseCurrency string            `gorm:"type:varchar(10);not null" json:"baseCurrency" binding:"required"` // 计价货币
+	Description  string            `gorm:"type:text" json:"description"`                                     // 描述信息
+	OwnerID      uint              `gorm:"index" json:"ownerId"`                                             // 创建者，只有创建者和管理员可以修改或删除
+	Components   []BasketComponent `gorm:"foreignKey:BasketID" json:"components" binding:"required,min=1,dive"`
+}
+
+// BasketComponent 篮子成分，Weight 表示该货币在篮子中的数量
+type BasketComponent struct {
+	ID       uint    `gorm:"primarykey" json:"_id"`
+	BasketID uint    `gorm:"not null;index" json:"basketId"`
+	Currency string  `gorm:"type:varchar(10);not null" json:"currency" binding:"required"`
+	Weight   float64 `gorm:"not null" json:"weight" binding:"required,gt=0"`
+}
+
+// BasketValue 篮子在某一时刻的指数值，由 ExchangeRate 历史计算得出
+type BasketValue struct {
+	ID       uint      `gorm:"primarykey" json:"_id"`
+	BasketID uint      `gorm:"not null;index:idx_basket_date" json:"basketId"`
+	Value    float64   `gorm:"not null" json:"value"`
+	Date     time.Time `gorm:"index:idx_basket_date" json:"date"`
+}
@@ -0,0 +1,22 @@
+package artice
+
+import "time"
+
+// ReadingList 用户命名的阅读列表，同一用户的列表名不能重复
+type ReadingList struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_user_list_name" json:"userId"`
+	Name      string    `gorm:"type:varchar(100);not null;uniqueIndex:idx_user_list_name" json:"name" binding:"required"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	Count     int64     `gorm:"-" json:"count"` // 列表中未删除的文章数，仅用于响应
+}
+
+// Bookmark 收藏到阅读列表中的文章；UserID 冗余保存，用于判断文章是否已被当前用户收藏
+type Bookmark struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	ListID    uint      `gorm:"not null;uniqueIndex:idx_list_article" json:"listId"`
+	ArticleID uint      `gorm:"not null;uniqueIndex:idx_list_article;index;index:idx_user_article,priority:2" json:"articleId"`
+	UserID    uint      `gorm:"not null;index:idx_user_article,priority:1" json:"userId"`
+	CreatedAt time.Time `json:"createdAt"`
+}
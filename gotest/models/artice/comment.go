@@ -0,0 +1,23 @@
+package artice
+
+import "time"
+
+// DeletedCommentPlaceholder 已删除评论在回复树中显示的占位内容
+const DeletedCommentPlaceholder = "[deleted]"
+
+// Comment 文章评论，通过 ParentID 组成回复树，RootID 指向所在楼层的顶级评论。
+// 删除评论只做标记，保留其在回复树中的位置。
+type Comment struct {
+	ID        uint       `gorm:"primarykey" json:"id"`
+	ArticleID uint       `gorm:"not null;index" json:"articleId"`
+	AuthorID  uint       `gorm:"not null;index" json:"authorId"`
+	ParentID  *uint      `gorm:"index" json:"parentId"`        // 回复的评论，为空表示顶级评论
+	RootID    *uint      `gorm:"index" json:"rootId"`          // 所在楼层的顶级评论，顶级评论为空
+	Content   string     `gorm:"type:text" json:"content"`     // 评论内容，删除后清空
+	Deleted   bool       `gorm:"default:false" json:"deleted"` // 是否已删除
+	Pending   bool       `gorm:"default:false" json:"pending"` // 命中敏感词待审核，审核通过前仅作者和管理员可见
+	Hidden    bool       `gorm:"default:false" json:"hidden"`  // 被多人举报后自动隐藏，仅作者和管理员可见
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+	Replies   []*Comment `gorm:"-" json:"replies,omitempty"` // 下级回复，仅用于返回评论树
+}
@@ -2,10 +2,21 @@ package artice
 
 import "time"
 
+// 汇率来源类型
+const (
+	RateSourceManual    = "manual"    // 用户手动录入
+	RateSourceProvider  = "provider"  // 命名的外部数据提供方
+	RateSourceImport    = "import"    // 批量导入
+	RateSourceConsensus = "consensus" // 多来源共识（中位数），仅用于发布，不入库
+)
+
 type ExchangeRate struct {
 	ID           uint      `gorm:"primarykey" json:"_id"`
 	FromCurrency string    `json:"fromCurrency" binding:"required"`
 	ToCurrency   string    `json:"toCurrency" binding:"required"`
 	Rate         float64   `json:"rate" binding:"required"`
 	Date         time.Time `json:"date"`
+	SourceType   string    `gorm:"type:varchar(20);default:'manual';index" json:"sourceType"` // 来源类型：manual/provider/import
+	Source       string    `gorm:"type:varchar(100)" json:"source"`                           // 来源名称：用户名、提供方名称或导入批次号
+	UserID       *uint     `json:"userId,omitempty"`                                          // 录入该汇率的用户
 }
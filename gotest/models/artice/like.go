@@ -0,0 +1,11 @@
+package artice
+
+import "time"
+
+// ArticleLike 用户对文章的点赞记录，由后台任务从 Redis 同步而来
+type ArticleLike struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	ArticleID uint      `gorm:"not null;uniqueIndex:idx_article_user" json:"articleId"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_article_user;index" json:"userId"`
+	CreatedAt time.Time `json:"createdAt"`
+}
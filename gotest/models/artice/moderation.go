@@ -0,0 +1,39 @@
+package artice
+
+import "time"
+
+// 审核对象的类型
+const (
+	ModerationTargetArticle = "article"
+	ModerationTargetComment = "comment"
+)
+
+// 审核状态
+const (
+	ModerationPending  = "pending"  // 待审核
+	ModerationApproved = "approved" // 审核通过，内容已公开
+	ModerationRejected = "rejected" // 审核驳回：文章退回草稿，评论被删除
+)
+
+// SensitiveWord 敏感词库中的一个词，Policy 为命中后的处理策略：mask、review 或 reject
+type SensitiveWord struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	Word      string    `gorm:"type:varchar(100);not null;uniqueIndex" json:"word"`
+	Policy    string    `gorm:"type:varchar(20);not null" json:"policy"`
+	CreatedBy uint      `json:"createdBy"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// ModerationItem 审核队列中的一项。同一对象重新提交时，旧的待审核项被新的替换
+type ModerationItem struct {
+	ID         uint       `gorm:"primarykey" json:"id"`
+	TargetType string     `gorm:"type:varchar(20);not null;index:idx_moderation_target" json:"targetType"`
+	TargetID   uint       `gorm:"not null;index:idx_moderation_target" json:"targetId"`
+	AuthorID   uint       `gorm:"not null;index" json:"authorId"`                         // 内容作者
+	Words      []string   `gorm:"serializer:json;type:text" json:"words"`                 // 命中的 review 策略的词
+	Status     string     `gorm:"type:varchar(20);default:'pending';index" json:"status"` // pending、approved、rejected
+	ReviewerID *uint      `json:"reviewerId"`                                             // 处理该项的管理员
+	Reason     string     `gorm:"type:varchar(255)" json:"reason"`                        // 驳回原因
+	ReviewedAt *time.Time `json:"reviewedAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
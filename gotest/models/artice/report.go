@@ -0,0 +1,42 @@
+package artice
+
+import "time"
+
+// 举报对象的类型
+const (
+	ReportTargetArticle = "article"
+	ReportTargetComment = "comment"
+	ReportTargetUser    = "user"
+)
+
+// 举报状态
+const (
+	ReportPending   = "pending"   // 待处理
+	ReportDismissed = "dismissed" // 驳回举报，被隐藏的内容恢复
+	ReportRemoved   = "removed"   // 内容已移除
+	ReportBanned    = "banned"    // 用户已封禁，被举报的内容一并移除
+)
+
+// Report 用户对文章、评论或用户的举报。同一用户对同一对象只能有一条待处理的举报
+type Report struct {
+	ID         uint       `gorm:"primarykey" json:"id"`
+	TargetType string     `gorm:"type:varchar(20);not null;index:idx_report_target" json:"targetType"`
+	TargetID   uint       `gorm:"not null;index:idx_report_target" json:"targetId"`
+	ReporterID uint       `gorm:"not null;index" json:"reporterId"`
+	Reason     string     `gorm:"type:varchar(500);not null" json:"reason"`
+	Status     string     `gorm:"type:varchar(20);default:'pending';index" json:"status"`
+	ResolverID *uint      `json:"resolverId"`                    // 处理该举报的管理员
+	Note       string     `gorm:"type:varchar(255)" json:"note"` // 处理说明，会附在给举报人的通知中
+	ResolvedAt *time.Time `json:"resolvedAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+// Notification 发给用户的站内通知
+type Notification struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	UserID    uint      `gorm:"not null;index:idx_notification_user" json:"userId"`
+	Type      string    `gorm:"type:varchar(30);not null" json:"type"` // 通知类型，如 report_resolved
+	Message   string    `gorm:"type:varchar(500)" json:"message"`
+	Read      bool      `gorm:"default:false;index:idx_notification_user" json:"read"`
+	CreatedAt time.Time `json:"createdAt"`
+}
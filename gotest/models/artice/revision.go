@@ -0,0 +1,17 @@
+package artice
+
+import "time"
+
+// ArticleRevision 文章每次保存时的快照，Number 在同一篇文章内从 1 开始递增
+type ArticleRevision struct {
+	ID         uint      `gorm:"primarykey" json:"id"`
+	ArticleID  uint      `gorm:"not null;uniqueIndex:idx_article_revision" json:"articleId"`
+	Number     int       `gorm:"not null;uniqueIndex:idx_article_revision" json:"number"`
+	EditorID   uint      `gorm:"index" json:"editorId"` // 本次保存的用户
+	Title      string    `json:"title"`
+	Content    string    `gorm:"type:longtext" json:"content,omitempty"` // Markdown 源文本，列表中省略
+	Preview    string    `gorm:"type:text" json:"preview,omitempty"`
+	CategoryID *uint     `json:"categoryId"`
+	Tags       []string  `gorm:"serializer:json" json:"tags"` // 保存时的标签名
+	CreatedAt  time.Time `json:"createdAt"`
+}
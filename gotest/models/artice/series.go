@@ -0,0 +1,46 @@
+package artice
+
+import "time"
+
+// Series 作者的系列文章，如分多篇发布的教程。系列中的文章按 Position 排列
+type Series struct {
+	ID          uint      `gorm:"primarykey" json:"id"`
+	AuthorID    uint      `gorm:"not null;index" json:"authorId"`
+	Title       string    `gorm:"type:varchar(200);not null" json:"title"`
+	Description string    `gorm:"type:text" json:"description"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// SeriesArticle 系列中的一篇文章及其位置，一篇文章最多属于一个系列
+type SeriesArticle struct {
+	ID        uint `gorm:"primarykey" json:"-"`
+	SeriesID  uint `gorm:"not null;index:idx_series_position,priority:1" json:"seriesId"`
+	ArticleID uint `gorm:"not null;uniqueIndex" json:"articleId"`
+	Position  int  `gorm:"not null;index:idx_series_position,priority:2" json:"position"`
+}
+
+// SeriesRead 读者已读过的系列文章，用于计算阅读进度
+type SeriesRead struct {
+	ID        uint      `gorm:"primarykey" json:"-"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_series_read,priority:1;index:idx_reader_series,priority:1" json:"userId"`
+	SeriesID  uint      `gorm:"not null;index:idx_reader_series,priority:2" json:"seriesId"`
+	ArticleID uint      `gorm:"not null;uniqueIndex:idx_series_read,priority:2" json:"articleId"`
+	ReadAt    time.Time `gorm:"autoCreateTime" json:"readAt"`
+}
+
+// SeriesLink 系列中相邻的一篇文章
+type SeriesLink struct {
+	ID    uint   `json:"id"`
+	Title string `json:"title"`
+}
+
+// SeriesNav 文章在系列中的位置及前后篇，仅用于响应。位置和总数只计入读者可见的文章
+type SeriesNav struct {
+	ID       uint        `json:"id"`
+	Title    string      `json:"title"`
+	Position int         `json:"position"` // 从 1 开始
+	Total    int         `json:"total"`
+	Prev     *SeriesLink `json:"prev"`
+	Next     *SeriesLink `json:"next"`
+}
@@ -0,0 +1,15 @@
+package artice
+
+// Tag 文章标签，与文章为多对多关系（关联表 article_tags）
+type Tag struct {
+	ID   uint   `gorm:"primarykey" json:"id"`
+	Name string `gorm:"type:varchar(50);not null;unique" json:"name" binding:"required"` // 标签名，统一为小写
+}
+
+// Category 文章分类，通过 ParentID 组成树形结构
+type Category struct {
+	ID       uint       `gorm:"primarykey" json:"id"`
+	Name     string     `gorm:"type:varchar(50);not null" json:"name" binding:"required"` // 分类名称
+	ParentID *uint      `gorm:"index" json:"parentId"`                                    // 上级分类，为空表示顶级分类
+	Children []Category `gorm:"-" json:"children,omitempty"`                              // 下级分类，仅用于返回分类树
+}
@@ -0,0 +1,12 @@
+package artice
+
+import "time"
+
+// ArticleViewDaily 文章每天的浏览量和独立访客数（HyperLogLog 近似值），由后台任务从 Redis 同步而来
+type ArticleViewDaily struct {
+	ID          uint      `gorm:"primarykey" json:"-"`
+	ArticleID   uint      `gorm:"not null;uniqueIndex:idx_article_date" json:"articleId"`
+	Date        time.Time `gorm:"type:date;not null;uniqueIndex:idx_article_date;index" json:"date"`
+	Views       int64     `gorm:"default:0" json:"views"`
+	UniqueViews int64     `gorm:"default:0" json:"uniqueViews"`
+}
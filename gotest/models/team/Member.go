@@ -5,6 +5,18 @@ import (
 	"gorm.io/gorm"
 )
 
+// 团队角色
+const (
+	RoleOwner  = "owner"
+	RoleAdmin  = "admin"
+	RoleMember = "member"
+)
+
+// ValidRole 判断角色是否有效
+func ValidRole(role string) bool {
+	return role == RoleOwner || role == RoleAdmin || role == RoleMember
+}
+
 // TeamMember 团队成员模型，存储团队成员信息以及权限
 type TeamMember struct {
 	gorm.Model
@@ -0,0 +1,18 @@
+package user
+
+import "time"
+
+// 关注对象的类型
+const (
+	FollowTargetUser = "user"
+	FollowTargetTeam = "team"
+)
+
+// Follow 用户对其他用户或团队的关注，同一对象只能关注一次
+type Follow struct {
+	ID         uint      `gorm:"primarykey" json:"id"`
+	FollowerID uint      `gorm:"not null;uniqueIndex:idx_follow" json:"followerId"`
+	TargetType string    `gorm:"type:varchar(10);not null;uniqueIndex:idx_follow;index:idx_follow_target" json:"targetType"`
+	TargetID   uint      `gorm:"not null;uniqueIndex:idx_follow;index:idx_follow_target" json:"targetId"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
@@ -0,0 +1,73 @@
+package user
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// defaultCountryCode 没有国家码的手机号按中国大陆号码处理
+const defaultCountryCode = "86"
+
+var (
+	// phoneLike 由数字和常见分隔符组成，可带前导 +
+	phoneLike = regexp.MustCompile(`^\+?[0-9][0-9 ()\-.]*$`)
+	// e164 国际电话号码格式：+ 加国家码，共不超过 15 位数字
+	e164 = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+)
+
+// ErrInvalidPhone 手机号无法规范化为 E.164 格式
+var ErrInvalidPhone = errors.New("invalid phone number")
+
+// IsPhoneLike 判断字符串是否由数字和常见分隔符组成，看起来像手机号
+func IsPhoneLike(s string) bool {
+	return phoneLike.MatchString(strings.TrimSpace(s))
+}
+
+// NormalizePhone 把手机号规范化为 E.164 格式（如 +8613800138000）。
+// 去掉空格、括号等分隔符；00 开头视为国际前缀，没有国家码时使用默认国家码
+func NormalizePhone(phone string) (string, error) {
+	phone = strings.TrimSpace(phone)
+	if !phoneLike.MatchString(phone) {
+		return "", ErrInvalidPhone
+	}
+
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, phone)
+	switch {
+	case strings.HasPrefix(phone, "+"):
+	case strings.HasPrefix(digits, "00"):
+		digits = digits[2:]
+	default:
+		digits = defaultCountryCode + strings.TrimPrefix(digits, "0")
+	}
+
+	normalized := "+" + digits
+	if !e164.MatchString(normalized) {
+		return "", ErrInvalidPhone
+	}
+	return normalized, nil
+}
+
+// BeforeSave 写入前把手机号规范化为 E.164 格式，与登录时的查询保持一致；空字符串按未设置处理
+func (u *User) BeforeSave(tx *gorm.DB) error {
+	if u.Phone == nil {
+		return nil
+	}
+	if strings.TrimSpace(*u.Phone) == "" {
+		u.Phone = nil
+		return nil
+	}
+	phone, err := NormalizePhone(*u.Phone)
+	if err != nil {
+		return err
+	}
+	u.Phone = &phone
+	return nil
+}
@@ -0,0 +1,17 @@
+package user
+
+import "time"
+
+// RefreshToken 服务端保存的刷新令牌。每次刷新都会吊销旧令牌并签发同一族的新令牌，
+// 已吊销的令牌再次被使用说明令牌可能已泄露，此时吊销整族令牌
+type RefreshToken struct {
+	ID              uint       `gorm:"primarykey"`
+	UserID          uint       `gorm:"not null;index"`
+	TokenHash       string     `gorm:"type:char(64);not null;uniqueIndex"` // 令牌的 SHA-256 哈希，不保存明文
+	Family          string     `gorm:"type:char(32);not null;index"`       // 令牌族，同一次登录轮换出的令牌属于同一族
+	AccessJTI       string     `gorm:"type:char(32)"`                      // 与该令牌一起签发的访问令牌，吊销时一并加入黑名单
+	AccessExpiresAt time.Time  // 该访问令牌的过期时间
+	ExpiresAt       time.Time  `gorm:"index"`
+	RevokedAt       *time.Time // 吊销时间，刷新、登出或检测到重复使用时设置
+	CreatedAt       time.Time
+}
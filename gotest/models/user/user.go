@@ -2,6 +2,9 @@ package user
 
 import "gorm.io/gorm"
 
+// AdminLevel 管理员的最低账号等级，达到该等级的用户可以管理他人的内容
+const AdminLevel = 10
+
 type User struct {
 	gorm.Model
 	Username string  `gorm:"unique"` // 用户名
@@ -14,3 +17,22 @@ type User struct {
 	IsBanned bool    `gorm:"default:false"` // 是否封禁，默认不封禁
 	Pkg      *string `gorm:"unique"`        // 微信，支持微信登录
 }
+
+// 系统角色，写入访问令牌
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// IsAdmin 判断用户是否为管理员
+func (u *User) IsAdmin() bool {
+	return u.Level >= AdminLevel
+}
+
+// Roles 用户的系统角色，由账号等级决定
+func (u *User) Roles() []string {
+	if u.IsAdmin() {
+		return []string{RoleUser, RoleAdmin}
+	}
+	return []string{RoleUser}
+}
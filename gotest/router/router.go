@@ -22,7 +22,7 @@ func SetupRouter() *gin.Engine {
 		// 允许的源，只有来自 http://localhost:5173 的请求会被接受
 		AllowOrigins: []string{"http://localhost:5173"},
 		// 允许的 HTTP 方法
-		AllowMethods: []string{"GET", "POST", "OPTIONS"},
+		AllowMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 		// 允许的请求头部
 		AllowHeaders: []string{"Origin", "Content-Type", "Authorization"},
 		// 允许暴露的响应头部
@@ -42,35 +42,155 @@ func SetupRouter() *gin.Engine {
 		auth.POST("/login", controllers.Login)
 		// 注册接口，使用 POST 请求
 		auth.POST("/register", controllers.Register)
+		// 用刷新令牌换取新的令牌
+		auth.POST("/refresh", controllers.RefreshToken)
 		// 发送验证码，使用POST请求
 		auth.POST("/send", controllers.SendVerificationCode)
 	}
 
+	// 创建一个路由组，用于文章订阅（RSS、Atom、JSON Feed），无需登录
+	feeds := r.Group("/feeds")
+	{
+		feeds.GET("/articles.rss", controllers.GetArticleFeedRSS)
+		feeds.GET("/articles.atom", controllers.GetArticleFeedAtom)
+		feeds.GET("/articles.json", controllers.GetArticleFeedJSON)
+	}
+
+	// 本地存储的签名下载地址，由签名校验代替登录
+	r.GET("/media/*key", controllers.ServeMedia)
+
 	// 创建一个路由组，用于主要的 API 路由（以 /api 为前缀）
 	api := r.Group("/api")
 	// 获取汇率接口，使用 GET 请求
 	api.GET("/exchangeRates", controllers.GetExchangeRates)
+	// 获取多来源共识汇率，使用 GET 请求
+	api.GET("/exchangeRates/consensus", controllers.GetConsensusRates)
+	// 获取货币篮子及其指数值，使用 GET 请求
+	api.GET("/baskets", controllers.GetBaskets)
+	api.GET("/baskets/:id", controllers.GetBasketByID)
+	api.GET("/baskets/:id/values", controllers.GetBasketValues)
+	// 根据文章 ID 获取单篇文章，匿名访客只能看到公开文章，浏览按 IP 和 User-Agent 计为独立访客
+	api.GET("/articles/:id", middlewares.OptionalAuthMiddleWare(), controllers.GetArticleByID)
 
 	// 使用 AuthMiddleWare 中间件来保护以下接口，需要身份验证
 	api.Use(middlewares.AuthMiddleWare())
 	{
+		// 登出接口，吊销当前令牌；吊销指定令牌接口，仅管理员可用
+		api.POST("/auth/logout", controllers.Logout)
+		api.POST("/auth/revoke", controllers.RevokeTokens)
 		// 创建汇率接口，使用 POST 请求
 		api.POST("/exchangeRates", controllers.CreateExchangeRate)
+		// 批量导入汇率接口，使用 POST 请求
+		api.POST("/exchangeRates/import", controllers.ImportExchangeRates)
+		// 创建、更新、删除货币篮子
+		api.POST("/baskets", controllers.CreateBasket)
+		api.PUT("/baskets/:id", controllers.UpdateBasket)
+		api.DELETE("/baskets/:id", controllers.DeleteBasket)
 		// 创建文章接口，使用 POST 请求
 		api.POST("/articles", controllers.CreateArticle)
 		// 获取所有文章接口，使用 GET 请求
 		api.GET("/articles", controllers.GetArticles)
-		// 根据文章 ID 获取单篇文章，使用 GET 请求
-		api.GET("/articles/:id", controllers.GetArticleByID)
+		// 热门文章流，使用 GET 请求
+		api.GET("/articles/hot", controllers.GetHotArticles)
+		// 全文检索文章接口，使用 GET 请求
+		api.GET("/articles/search", controllers.SearchArticles)
+		// 更新、删除文章接口，仅作者或管理员可用
+		api.PUT("/articles/:id", controllers.UpdateArticle)
+		api.DELETE("/articles/:id", controllers.DeleteArticle)
+		// 文章修订历史接口：版本列表、单个版本、版本比较和恢复，仅作者或管理员可用
+		api.GET("/articles/:id/revisions", controllers.GetArticleRevisions)
+		api.GET("/articles/:id/revisions/diff", controllers.DiffArticleRevisions)
+		api.GET("/articles/:id/revisions/:number", controllers.GetArticleRevision)
+		api.POST("/articles/:id/revisions/:number/restore", controllers.RestoreArticleRevision)
+		// 文章浏览统计接口：按天的浏览量和独立访客数，仅作者或管理员可用
+		api.GET("/articles/:id/views", controllers.GetArticleViews)
+
+		// 阅读列表接口：创建、重命名、删除列表，收藏和取消收藏文章，分页获取列表中的文章
+		api.POST("/lists", controllers.CreateReadingList)
+		api.GET("/lists", controllers.GetReadingLists)
+		api.PUT("/lists/:id", controllers.RenameReadingList)
+		api.DELETE("/lists/:id", controllers.DeleteReadingList)
+		api.GET("/lists/:id/articles", controllers.GetReadingListArticles)
+		api.PUT("/lists/:id/articles/:articleId", controllers.AddBookmark)
+		api.DELETE("/lists/:id/articles/:articleId", controllers.RemoveBookmark)
+
+		// 系列接口：创建、修改、删除系列，添加、移出、移动和重排系列中的文章，系列主页及阅读进度
+		api.POST("/series", controllers.CreateSeries)
+		api.GET("/series/:id", controllers.GetSeries)
+		api.PUT("/series/:id", controllers.UpdateSeries)
+		api.DELETE("/series/:id", controllers.DeleteSeries)
+		api.POST("/series/:id/articles", controllers.AddSeriesArticle)
+		api.PUT("/series/:id/articles", controllers.ReorderSeries)
+		api.PUT("/series/:id/articles/:articleId", controllers.MoveSeriesArticle)
+		api.DELETE("/series/:id/articles/:articleId", controllers.RemoveSeriesArticle)
+
+		// 媒体接口：上传图片、获取和删除附件、获取文章的附件
+		api.POST("/media", controllers.UploadMedia)
+		api.GET("/media/:id", controllers.GetMedia)
+		api.DELETE("/media/:id", controllers.DeleteMedia)
+		api.GET("/articles/:id/media", controllers.GetArticleMedia)
+
+		// 文章评论接口：发表评论或回复、分页获取评论树
+		api.POST("/articles/:id/comments", controllers.CreateComment)
+		api.GET("/articles/:id/comments", controllers.GetArticleComments)
+		// 编辑、删除评论接口，仅评论作者可用
+		api.PUT("/comments/:id", controllers.UpdateComment)
+		api.DELETE("/comments/:id", controllers.DeleteComment)
+
+		// 标签自动补全及文章数、分类树
+		api.GET("/tags", controllers.GetTags)
+		api.GET("/categories", controllers.GetCategories)
+		// 创建分类接口，仅管理员可用
+		api.POST("/categories", controllers.CreateCategory)
+
+		// 文章和作者排行榜（日榜、周榜、总榜）
+		api.GET("/rankings/articles", controllers.GetArticleRanking)
+		api.GET("/rankings/authors", controllers.GetAuthorRanking)
+		// 从数据库重建排行榜，仅管理员可用
+		api.POST("/rankings/rebuild", controllers.RebuildRankingsHandler)
+
+		// 敏感词库管理和内容审核队列，仅管理员可用
+		api.GET("/sensitive-words", controllers.GetSensitiveWords)
+		api.POST("/sensitive-words", controllers.CreateSensitiveWord)
+		api.POST("/sensitive-words/reload", controllers.ReloadSensitiveWords)
+		api.DELETE("/sensitive-words/:id", controllers.DeleteSensitiveWord)
+		api.GET("/moderation", controllers.GetModerationQueue)
+		api.POST("/moderation/:id/approve", controllers.ApproveModeration)
+		api.POST("/moderation/:id/reject", controllers.RejectModeration)
+
+		// 举报文章、评论或用户；举报队列和处理仅管理员可用
+		api.POST("/reports", controllers.CreateReport)
+		api.GET("/reports", controllers.GetReports)
+		api.POST("/reports/:id/resolve", controllers.ResolveReport)
+
+		// 用户和团队资料、关注和取消关注、粉丝和关注列表，以及关注对象的动态流
+		api.GET("/users/:id", controllers.GetUserProfile)
+		api.POST("/users/:id/follow", controllers.FollowUser)
+		api.DELETE("/users/:id/follow", controllers.UnfollowUser)
+		api.GET("/users/:id/followers", controllers.GetFollowers)
+		api.GET("/users/:id/following", controllers.GetFollowing)
+		api.GET("/teams/:id", controllers.GetTeamProfile)
+		api.POST("/teams/:id/follow", controllers.FollowTeam)
+		api.DELETE("/teams/:id/follow", controllers.UnfollowTeam)
+		api.GET("/feed", controllers.GetActivityFeed)
+
+		// 当前用户的站内通知
+		api.GET("/notifications", controllers.GetNotifications)
+		api.POST("/notifications/read-all", controllers.MarkAllNotificationsRead)
+		api.POST("/notifications/:id/read", controllers.MarkNotificationRead)
 
 		// 点赞文章接口，使用 POST 请求
 		api.POST("/articles/:id/like", controllers.LikeArticle)
+		// 取消点赞接口，使用 DELETE 请求
+		api.DELETE("/articles/:id/like", controllers.UnlikeArticle)
 		// 获取文章的点赞数接口，使用 GET 请求
 		api.GET("/articles/:id/like", controllers.GetArticleLikes)
 	}
 
 	// TeamManagement 路由分组
+	// 团队管理需要登录，拥有者和操作者都取自当前用户
 	teamMg := r.Group("/teamMg")
+	teamMg.Use(middlewares.AuthMiddleWare())
 	{
 		// 创建团队
 		teamMg.POST("/createteam", TeamManagement.CreateTeam) // 创建新团队
@@ -3,9 +3,10 @@ package rsp
 // ErrorMessages 错误码和错误信息的映射表
 var ErrorMessages = map[int]string{
 	// 用户相关错误
-	10001: "用户已存在",    // 用户已经存在
-	10002: "用户不存在",    // 用户不存在
-	10003: "用户名或密码错误", // 用户名或密码不匹配
+	10001: "用户已存在",   // 用户已经存在
+	10002: "用户不存在",   // 用户不存在
+	10003: "账号或密码错误", // 账号不存在或密码不匹配，不区分两者以免泄露账号是否存在
+	10004: "用户已被封禁",  // 用户因违规被管理员封禁
 
 	// 团队相关错误
 	11001: "团队名称已存在",  // 同一用户下不能重复创建团队名称
@@ -21,6 +22,7 @@ var ErrorMessages = map[int]string{
 	// 系统业务逻辑错误
 	13001: "请求的数据不完整",  // 请求参数缺少必要字段
 	13002: "数据不符合业务规则", // 数据状态与业务逻辑要求不匹配
+	13003: "内容包含敏感词",   // 提交的内容命中敏感词库
 
 	// 数据库相关错误
 	20001: "数据库连接失败", // 数据库连接失败
@@ -39,6 +41,8 @@ var ErrorMessages = map[int]string{
 	// JWT 相关错误
 	50001: "JWT 生成失败", // JWT 生成失败
 	50002: "密码加密失败",   // 密码加密失败
+	50004: "登录已失效",    // 刷新令牌无效、已过期或已被吊销，需要重新登录
+	50005: "登录状态异常",   // 刷新令牌被重复使用，该次登录的所有令牌已被吊销
 
 	// 系统数据库问题
 	60001: "数据库问题", // 服务器数据库问题
@@ -0,0 +1,186 @@
+package search
+
+import (
+	"exchangeapp/global"
+	"exchangeapp/models/artice"
+	"math"
+	"sort"
+	"sync"
+)
+
+// titleBoost 标题中的词在评分时的权重倍数
+const titleBoost = 2
+
+// BM25 参数
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// MemorySearcher 进程内倒排索引实现，使用 BM25 评分，适用于测试和单机部署
+type MemorySearcher struct {
+	mu       sync.RWMutex
+	postings map[string]map[uint]int // 检索词 -> 文章ID -> 词频
+	lengths  map[uint]int            // 文章ID -> 文档长度（词数）
+	articles map[uint]artice.Article // 文章ID -> 文章内容，用于生成摘要
+	total    int                     // 所有文档长度之和
+}
+
+// NewMemorySearcher 创建一个空的内存索引
+func NewMemorySearcher() *MemorySearcher {
+	return &MemorySearcher{
+		postings: make(map[string]map[uint]int),
+		lengths:  make(map[uint]int),
+		articles: make(map[uint]artice.Article),
+	}
+}
+
+// NewMemorySearcherFromDB 创建内存索引，并载入数据库中已有的文章
+func NewMemorySearcherFromDB() (*MemorySearcher, error) {
+	s := NewMemorySearcher()
+
+	var articles []artice.Article
+	if err := global.Db.Find(&articles).Error; err != nil {
+		return nil, err
+	}
+	for _, article := range articles {
+		if err := s.Index(article); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// Index 新增或更新一篇文章的索引，只有已发布的公开文章可以被检索
+func (s *MemorySearcher) Index(article artice.Article) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.remove(article.ID)
+	if !article.IsPublic() {
+		return nil
+	}
+
+	freqs := make(map[string]int)
+	length := 0
+	for _, token := range Tokenize(article.Title) {
+		freqs[token] += titleBoost
+		length += titleBoost
+	}
+	for _, field := range []string{article.Content, article.Preview} {
+		for _, token := range Tokenize(field) {
+			freqs[token]++
+			length++
+		}
+	}
+
+	for token, freq := range freqs {
+		docs, ok := s.postings[token]
+		if !ok {
+			docs = make(map[uint]int)
+			s.postings[token] = docs
+		}
+		docs[article.ID] = freq
+	}
+	s.lengths[article.ID] = length
+	s.articles[article.ID] = article
+	s.total += length
+
+	return nil
+}
+
+// Remove 从索引中移除一篇文章
+func (s *MemorySearcher) Remove(id uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.remove(id)
+	return nil
+}
+
+// remove 移除文章的全部倒排记录，调用方需持有写锁
+func (s *MemorySearcher) remove(id uint) {
+	article, ok := s.articles[id]
+	if !ok {
+		return
+	}
+
+	for _, field := range []string{article.Title, article.Content, article.Preview} {
+		for _, token := range Tokenize(field) {
+			if docs, ok := s.postings[token]; ok {
+				delete(docs, id)
+				if len(docs) == 0 {
+					delete(s.postings, token)
+				}
+			}
+		}
+	}
+	s.total -= s.lengths[id]
+	delete(s.lengths, id)
+	delete(s.articles, id)
+}
+
+// Search 按 BM25 评分从高到低返回匹配的文章
+func (s *MemorySearcher) Search(query string, limit, offset int) ([]Result, error) {
+	terms := uniqueTerms(Tokenize(query))
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if len(s.articles) == 0 || len(terms) == 0 {
+		return []Result{}, nil
+	}
+
+	n := float64(len(s.articles))
+	avgLength := float64(s.total) / n
+	scores := make(map[uint]float64)
+	for _, term := range terms {
+		docs := s.postings[term]
+		if len(docs) == 0 {
+			continue
+		}
+		df := float64(len(docs))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		for id, freq := range docs {
+			tf := float64(freq)
+			norm := 1 - bm25B + bm25B*float64(s.lengths[id])/avgLength
+			scores[id] += idf * tf * (bm25K1 + 1) / (tf + bm25K1*norm)
+		}
+	}
+
+	ids := make([]uint, 0, len(scores))
+	for id := range scores {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if scores[ids[i]] != scores[ids[j]] {
+			return scores[ids[i]] > scores[ids[j]]
+		}
+		return ids[i] > ids[j]
+	})
+
+	if offset >= len(ids) {
+		return []Result{}, nil
+	}
+	ids = ids[offset:min(len(ids), offset+limit)]
+
+	results := make([]Result, 0, len(ids))
+	for _, id := range ids {
+		results = append(results, buildResult(s.articles[id], terms, scores[id]))
+	}
+	return results, nil
+}
+
+// uniqueTerms 去除重复的检索词，保持原有顺序
+func uniqueTerms(tokens []string) []string {
+	seen := make(map[string]bool, len(tokens))
+	terms := tokens[:0:0]
+	for _, token := range tokens {
+		if !seen[token] {
+			seen[token] = true
+			terms = append(terms, token)
+		}
+	}
+	return terms
+}
@@ -0,0 +1,175 @@
+package search
+
+import (
+	"exchangeapp/models/artice"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func newArticle(id uint, title, content string) artice.Article {
+	return artice.Article{
+		Model:      gorm.Model{ID: id},
+		Title:      title,
+		Content:    content,
+		Status:     artice.StatusPublished,
+		Visibility: artice.VisibilityPublic,
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"Hello, World!", []string{"hello", "world"}},
+		{"汇率", []string{"汇率"}},
+		{"人民币汇率", []string{"人民", "民币", "币汇", "汇率"}},
+		{"美", []string{"美"}},
+		{"USD兑人民币2024年", []string{"usd", "兑人", "人民", "民币", "2024", "年"}},
+		{"日本語とカタカナ", []string{"日本", "本語", "語と", "とカ", "カタ", "タカ", "カナ"}},
+		{"  ,.!  ", nil},
+	}
+	for _, tt := range tests {
+		if got := Tokenize(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Tokenize(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func resultIDs(results []Result) []uint {
+	ids := make([]uint, 0, len(results))
+	for _, r := range results {
+		ids = append(ids, r.ID)
+	}
+	return ids
+}
+
+func TestSearchRanking(t *testing.T) {
+	s := NewMemorySearcher()
+	for _, a := range []artice.Article{
+		newArticle(1, "市场周报", "本周汇率小幅波动，股市上涨。"),
+		newArticle(2, "汇率走势分析", "人民币汇率持续走强，汇率弹性增加。"),
+		newArticle(3, "旅行日记", "去了很多地方，吃了很多美食。"),
+		newArticle(4, "Exchange notes", "Exchange rate exchange rate exchange."),
+	} {
+		if err := s.Index(a); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	results, err := s.Search("汇率", 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 标题命中且正文词频更高的文章排在前面，不相关的文章不出现
+	if got, want := resultIDs(results), []uint{2, 1}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Search ids = %v, want %v", got, want)
+	}
+	if results[0].Score <= results[1].Score {
+		t.Errorf("scores not descending: %v", results)
+	}
+
+	if results, _ := s.Search("EXCHANGE", 10, 0); !reflect.DeepEqual(resultIDs(results), []uint{4}) {
+		t.Errorf("case-insensitive search = %v", resultIDs(results))
+	}
+	if results, _ := s.Search("汇率", 1, 1); !reflect.DeepEqual(resultIDs(results), []uint{1}) {
+		t.Errorf("paged search = %v", resultIDs(results))
+	}
+	if results, _ := s.Search("汇率", 10, 5); len(results) != 0 {
+		t.Errorf("offset past the end = %v", resultIDs(results))
+	}
+	if results, _ := s.Search("比特币", 10, 0); len(results) != 0 {
+		t.Errorf("unmatched query = %v", resultIDs(results))
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	got, ok := Highlight("人民币<汇率>持续走强", []string{"汇率"})
+	if !ok {
+		t.Fatal("no match")
+	}
+	if want := "人民币&lt;<em>汇率</em>&gt;持续走强"; got != want {
+		t.Errorf("Highlight = %q, want %q", got, want)
+	}
+
+	got, _ = Highlight("The Exchange rate", []string{"exchange", "rate"})
+	if want := "The <em>Exchange</em> <em>rate</em>"; got != want {
+		t.Errorf("Highlight = %q, want %q", got, want)
+	}
+
+	long := strings.Repeat("a", 100) + "汇率" + strings.Repeat("b", 100)
+	got, _ = Highlight(long, []string{"汇率"})
+	want := "…" + strings.Repeat("a", snippetRadius) + "<em>汇率</em>" + strings.Repeat("b", snippetRadius-2) + "…"
+	if got != want {
+		t.Errorf("Highlight long text = %q, want %q", got, want)
+	}
+
+	if _, ok := Highlight("没有命中", []string{"汇率"}); ok {
+		t.Error("Highlight reported a match")
+	}
+}
+
+func TestSearchResultHighlighting(t *testing.T) {
+	s := NewMemorySearcher()
+	if err := s.Index(newArticle(1, "汇率<周报>", "今天的汇率上涨。")); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := s.Search("汇率", 10, 0)
+	if err != nil || len(results) != 1 {
+		t.Fatalf("Search = %v, %v", results, err)
+	}
+	if want := "<em>汇率</em>&lt;周报&gt;"; results[0].Title != want {
+		t.Errorf("Title = %q, want %q", results[0].Title, want)
+	}
+	if want := "今天的<em>汇率</em>上涨。"; results[0].Snippet != want {
+		t.Errorf("Snippet = %q, want %q", results[0].Snippet, want)
+	}
+}
+
+func TestIndexUpdatesAndRemove(t *testing.T) {
+	s := NewMemorySearcher()
+	if err := s.Index(newArticle(1, "汇率", "美元")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Index(newArticle(2, "汇率", "欧元")); err != nil {
+		t.Fatal(err)
+	}
+
+	// 重新索引时旧内容的检索词被移除
+	if err := s.Index(newArticle(1, "股市", "日元")); err != nil {
+		t.Fatal(err)
+	}
+	if results, _ := s.Search("美元", 10, 0); len(results) != 0 {
+		t.Errorf("stale term still indexed: %v", resultIDs(results))
+	}
+	if results, _ := s.Search("日元", 10, 0); !reflect.DeepEqual(resultIDs(results), []uint{1}) {
+		t.Errorf("updated term = %v", resultIDs(results))
+	}
+	if results, _ := s.Search("汇率", 10, 0); !reflect.DeepEqual(resultIDs(results), []uint{2}) {
+		t.Errorf("after update = %v", resultIDs(results))
+	}
+
+	// 下线的文章不再被检索
+	draft := newArticle(2, "汇率", "欧元")
+	draft.Status = artice.StatusDraft
+	if err := s.Index(draft); err != nil {
+		t.Fatal(err)
+	}
+	if results, _ := s.Search("汇率", 10, 0); len(results) != 0 {
+		t.Errorf("unpublished article indexed: %v", resultIDs(results))
+	}
+
+	if err := s.Remove(1); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.postings) != 0 || len(s.lengths) != 0 || len(s.articles) != 0 || s.total != 0 {
+		t.Errorf("index not empty after removing every article: %+v", s)
+	}
+	if err := s.Remove(42); err != nil {
+		t.Errorf("Remove unknown id: %v", err)
+	}
+}
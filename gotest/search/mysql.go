@@ -0,0 +1,70 @@
+package search
+
+import (
+	"exchangeapp/global"
+	"exchangeapp/models/artice"
+)
+
+// fulltextIndex 文章表上的 FULLTEXT 索引名
+const fulltextIndex = "idx_articles_fulltext"
+
+// matchExpr 与 FULLTEXT 索引列保持一致的 MATCH 表达式
+const matchExpr = "MATCH(title, content, preview) AGAINST (? IN NATURAL LANGUAGE MODE)"
+
+// MySQLSearcher 基于 MySQL FULLTEXT 索引（ngram 解析器）的实现，索引由 InnoDB 随写入自动维护
+type MySQLSearcher struct{}
+
+// NewMySQLSearcher 创建 MySQL 检索实现，文章表上缺少 FULLTEXT 索引时自动创建
+func NewMySQLSearcher() (*MySQLSearcher, error) {
+	var count int64
+	err := global.Db.Raw("SELECT COUNT(*) FROM information_schema.statistics WHERE table_schema = DATABASE() AND table_name = ? AND index_name = ?",
+		"articles", fulltextIndex).Scan(&count).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if count == 0 {
+		if err := global.Db.Exec("ALTER TABLE articles ADD FULLTEXT INDEX " + fulltextIndex +
+			" (title, content, preview) WITH PARSER ngram").Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return &MySQLSearcher{}, nil
+}
+
+// Index InnoDB 会在写入时自动更新 FULLTEXT 索引，无需额外处理
+func (s *MySQLSearcher) Index(article artice.Article) error {
+	return nil
+}
+
+// Remove 软删除和未发布的文章由查询条件过滤，无需额外处理
+func (s *MySQLSearcher) Remove(id uint) error {
+	return nil
+}
+
+// Search 使用 MATCH ... AGAINST 的相关度评分排序
+func (s *MySQLSearcher) Search(query string, limit, offset int) ([]Result, error) {
+	var rows []struct {
+		artice.Article
+		Score float64
+	}
+
+	err := global.Db.Model(&artice.Article{}).
+		Select("articles.*, "+matchExpr+" AS score", query).
+		Where(matchExpr, query).
+		Where("articles.deleted_at IS NULL AND articles.status = ? AND articles.visibility = ?", artice.StatusPublished, artice.VisibilityPublic).
+		Order("score DESC").
+		Limit(limit).Offset(offset).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	terms := uniqueTerms(Tokenize(query))
+	results := make([]Result, 0, len(rows))
+	for _, row := range rows {
+		results = append(results, buildResult(row.Article, terms, row.Score))
+	}
+	return results, nil
+}
@@ -0,0 +1,186 @@
+package search
+
+import (
+	"exchangeapp/config"
+	"exchangeapp/models/artice"
+	"fmt"
+	"html"
+	"log"
+	"strings"
+	"unicode"
+)
+
+// Result 一条搜索结果，Title 和 Snippet 已经过 HTML 转义，命中的词用 <em> 标记
+type Result struct {
+	ID      uint    `json:"id"`
+	Title   string  `json:"title"`
+	Snippet string  `json:"snippet"`
+	Score   float64 `json:"score"`
+}
+
+// Searcher 文章全文检索接口
+type Searcher interface {
+	// Index 新增或更新一篇文章的索引；未发布的文章只从索引中移除
+	Index(article artice.Article) error
+	// Remove 从索引中移除一篇文章
+	Remove(id uint) error
+	// Search 按相关度从高到低返回匹配的文章
+	Search(query string, limit, offset int) ([]Result, error)
+}
+
+// Default 全局使用的检索实现，由 InitSearch 根据配置初始化
+var Default Searcher
+
+// InitSearch 根据配置选择检索实现：mysql（默认，FULLTEXT ngram）或 memory（进程内倒排索引）
+func InitSearch() {
+	var err error
+
+	switch config.AppConfig.Search.Engine {
+	case "", "mysql":
+		Default, err = NewMySQLSearcher()
+	case "memory":
+		Default, err = NewMemorySearcherFromDB()
+	default:
+		err = fmt.Errorf("unknown search engine %q", config.AppConfig.Search.Engine)
+	}
+
+	if err != nil {
+		log.Fatalf("全文检索初始化失败: %v", err)
+	}
+}
+
+// isCJK 判断是否为中日韩文字，这些文字之间没有空格分隔，按 ngram 切分
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r)
+}
+
+// Tokenize 将文本切分为检索词：拉丁文字按单词切分并转为小写，
+// 中日韩文字按二元组（bigram）切分，与 MySQL ngram 解析器的默认 ngram_token_size=2 保持一致
+func Tokenize(text string) []string {
+	var tokens []string
+	var word []rune
+	var cjk []rune
+
+	flushWord := func() {
+		if len(word) > 0 {
+			tokens = append(tokens, string(word))
+			word = word[:0]
+		}
+	}
+	flushCJK := func() {
+		if len(cjk) == 1 {
+			tokens = append(tokens, string(cjk))
+		}
+		for i := 0; i+1 < len(cjk); i++ {
+			tokens = append(tokens, string(cjk[i:i+2]))
+		}
+		cjk = cjk[:0]
+	}
+
+	for _, r := range text {
+		switch {
+		case isCJK(r):
+			flushWord()
+			cjk = append(cjk, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushCJK()
+			word = append(word, unicode.ToLower(r))
+		default:
+			flushWord()
+			flushCJK()
+		}
+	}
+	flushWord()
+	flushCJK()
+
+	return tokens
+}
+
+// snippetRadius 摘要中命中位置前后保留的字符数
+const snippetRadius = 40
+
+// Highlight 在 text 中标记 terms 的所有出现位置，返回以第一个命中处为中心的摘要；
+// 没有命中时返回 false
+func Highlight(text string, terms []string) (string, bool) {
+	return highlight(text, terms, snippetRadius)
+}
+
+// highlight 标记命中并截取摘要，radius 小于 0 时保留全文
+func highlight(text string, terms []string, radius int) (string, bool) {
+	runes := []rune(text)
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	marked := make([]bool, len(runes))
+	first := -1
+	for _, term := range terms {
+		t := []rune(term)
+		if len(t) == 0 {
+			continue
+		}
+		for i := 0; i+len(t) <= len(lower); i++ {
+			if string(lower[i:i+len(t)]) != term {
+				continue
+			}
+			for j := i; j < i+len(t); j++ {
+				marked[j] = true
+			}
+			if first == -1 || i < first {
+				first = i
+			}
+		}
+	}
+	if first == -1 {
+		return "", false
+	}
+
+	start, end := 0, len(runes)
+	if radius >= 0 {
+		start = max(0, first-radius)
+		end = min(len(runes), first+radius)
+	}
+
+	var sb strings.Builder
+	if start > 0 {
+		sb.WriteString("…")
+	}
+	for i := start; i < end; {
+		j := i
+		for j < end && marked[j] == marked[i] {
+			j++
+		}
+		segment := html.EscapeString(string(runes[i:j]))
+		if marked[i] {
+			sb.WriteString("<em>" + segment + "</em>")
+		} else {
+			sb.WriteString(segment)
+		}
+		i = j
+	}
+	if end < len(runes) {
+		sb.WriteString("…")
+	}
+
+	return sb.String(), true
+}
+
+// buildResult 为命中的文章生成带高亮的标题和摘要，摘要依次尝试正文和预览
+func buildResult(article artice.Article, terms []string, score float64) Result {
+	title, ok := highlight(article.Title, terms, -1)
+	if !ok {
+		title = html.EscapeString(article.Title)
+	}
+
+	snippet, ok := Highlight(article.Content, terms)
+	if !ok {
+		snippet, ok = Highlight(article.Preview, terms)
+	}
+	if !ok {
+		snippet = html.EscapeString(article.Preview)
+	}
+
+	return Result{ID: article.ID, Title: title, Snippet: snippet, Score: score}
+}
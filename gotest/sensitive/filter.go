@@ -0,0 +1,131 @@
+package sensitive
+
+import (
+	"strings"
+	"sync/atomic"
+	"unicode"
+)
+
+// 命中敏感词后的处理策略，按严重程度递增
+const (
+	PolicyMask   = "mask"   // 替换为掩码后照常发布
+	PolicyReview = "review" // 送入审核队列，审核通过前仅作者和管理员可见
+	PolicyReject = "reject" // 拒绝提交
+)
+
+// MaskRune 掩码字符。使用全角星号，避免在 Markdown 正文中被解析为强调标记
+const MaskRune = '＊'
+
+// ValidPolicy 判断策略是否有效
+func ValidPolicy(policy string) bool {
+	return policy == PolicyMask || policy == PolicyReview || policy == PolicyReject
+}
+
+// Word 词库中的一个词及其策略
+type Word struct {
+	Text   string
+	Policy string
+}
+
+// Result 一段文本的检查结果，各策略命中的词已去重
+type Result struct {
+	Text   string   // 把 mask 策略的词替换为掩码后的文本
+	Masked []string // 命中的 mask 策略的词
+	Review []string // 命中的 review 策略的词
+	Reject []string // 命中的 reject 策略的词
+}
+
+// Filter 按词库检查文本，构建后只读
+type Filter struct {
+	matcher  *Matcher
+	policies []string
+}
+
+// NewFilter 由词库构建过滤器。同一个词重复出现时取最严格的策略
+func NewFilter(words []Word) *Filter {
+	index := make(map[string]int, len(words))
+	var texts, policies []string
+	for _, w := range words {
+		text := strings.Map(unicode.ToLower, strings.TrimSpace(w.Text))
+		if text == "" {
+			continue
+		}
+		if i, ok := index[text]; ok {
+			if severity(w.Policy) > severity(policies[i]) {
+				policies[i] = w.Policy
+			}
+			continue
+		}
+		index[text] = len(texts)
+		texts = append(texts, text)
+		policies = append(policies, w.Policy)
+	}
+	return &Filter{matcher: NewMatcher(texts), policies: policies}
+}
+
+// Check 检查文本，返回各策略命中的词以及掩码后的文本
+func (f *Filter) Check(text string) Result {
+	result := Result{Text: text}
+	matches := f.matcher.FindAll(text)
+	if len(matches) == 0 {
+		return result
+	}
+
+	var runes []rune
+	seen := make(map[int]bool)
+	for _, m := range matches {
+		policy := f.policies[m.Word]
+		if policy == PolicyMask {
+			if runes == nil {
+				runes = []rune(text)
+			}
+			for i := m.Start; i < m.End; i++ {
+				runes[i] = MaskRune
+			}
+		}
+		if seen[m.Word] {
+			continue
+		}
+		seen[m.Word] = true
+
+		word := f.matcher.words[m.Word]
+		switch policy {
+		case PolicyMask:
+			result.Masked = append(result.Masked, word)
+		case PolicyReview:
+			result.Review = append(result.Review, word)
+		default:
+			result.Reject = append(result.Reject, word)
+		}
+	}
+	if runes != nil {
+		result.Text = string(runes)
+	}
+	return result
+}
+
+// severity 策略的严重程度，未知策略按 reject 处理
+func severity(policy string) int {
+	switch policy {
+	case PolicyMask:
+		return 0
+	case PolicyReview:
+		return 1
+	}
+	return 2
+}
+
+var current atomic.Pointer[Filter]
+
+// Current 返回当前生效的过滤器，词库尚未加载时返回空过滤器
+func Current() *Filter {
+	if f := current.Load(); f != nil {
+		return f
+	}
+	return NewFilter(nil)
+}
+
+// Replace 原子地替换当前生效的过滤器，正在进行的检查不受影响
+func Replace(f *Filter) {
+	current.Store(f)
+}
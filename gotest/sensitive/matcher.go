@@ -0,0 +1,111 @@
+package sensitive
+
+import "unicode"
+
+// Match 一次命中：Word 为词库中的下标，Start、End 为命中文本的字符（rune）区间 [Start, End)
+type Match struct {
+	Word  int
+	Start int
+	End   int
+}
+
+// acNode Aho–Corasick 自动机的一个状态
+type acNode struct {
+	next map[rune]int32
+	fail int32
+	out  []int32 // 在该状态结束的词，包含沿失败链可达的词
+}
+
+// Matcher 基于 Aho–Corasick 自动机的多模式匹配器，一次扫描即可找出文本中所有词的所有出现位置。
+// 匹配不区分大小写；构建后只读，可以被多个 goroutine 并发使用。
+type Matcher struct {
+	nodes []acNode
+	words []string
+}
+
+// NewMatcher 由词表构建匹配器，空词被忽略
+func NewMatcher(words []string) *Matcher {
+	m := &Matcher{nodes: []acNode{{}}, words: words}
+
+	for i, word := range words {
+		state := int32(0)
+		for _, r := range word {
+			r = unicode.ToLower(r)
+			next, ok := m.nodes[state].next[r]
+			if !ok {
+				if m.nodes[state].next == nil {
+					m.nodes[state].next = make(map[rune]int32)
+				}
+				next = int32(len(m.nodes))
+				m.nodes = append(m.nodes, acNode{})
+				m.nodes[state].next[r] = next
+			}
+			state = next
+		}
+		if state != 0 {
+			m.nodes[state].out = append(m.nodes[state].out, int32(i))
+		}
+	}
+
+	// 按广度优先顺序计算失败指针，保证处理一个状态时其失败状态已经完成
+	queue := make([]int32, 0, len(m.nodes))
+	for _, child := range m.nodes[0].next {
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		state := queue[0]
+		queue = queue[1:]
+		for r, child := range m.nodes[state].next {
+			fail := m.nodes[state].fail
+			for fail != 0 {
+				if _, ok := m.nodes[fail].next[r]; ok {
+					break
+				}
+				fail = m.nodes[fail].fail
+			}
+			if target, ok := m.nodes[fail].next[r]; ok && target != child {
+				m.nodes[child].fail = target
+			}
+			m.nodes[child].out = append(m.nodes[child].out, m.nodes[m.nodes[child].fail].out...)
+			queue = append(queue, child)
+		}
+	}
+	return m
+}
+
+// FindAll 返回文本中所有词的所有出现位置，按结束位置排序
+func (m *Matcher) FindAll(text string) []Match {
+	if len(m.nodes) == 1 {
+		return nil
+	}
+
+	var matches []Match
+	state := int32(0)
+	pos := 0
+	for _, r := range text {
+		r = unicode.ToLower(r)
+		for {
+			if next, ok := m.nodes[state].next[r]; ok {
+				state = next
+				break
+			}
+			if state == 0 {
+				break
+			}
+			state = m.nodes[state].fail
+		}
+		pos++
+		for _, w := range m.nodes[state].out {
+			matches = append(matches, Match{Word: int(w), Start: pos - runeCount(m.words[w]), End: pos})
+		}
+	}
+	return matches
+}
+
+func runeCount(s string) int {
+	n := 0
+	for range s {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,142 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LocalStorage 把对象保存在本地目录中，下载地址由 HMAC 签名并由应用自身提供下载
+type LocalStorage struct {
+	dir       string
+	urlPath   string // 下载路由的前缀，如 /media
+	secret    []byte
+	maxExpiry time.Duration // 下载地址有效期的上限
+}
+
+// placeholderSecret 示例配置中的占位密钥，不允许直接使用
+const placeholderSecret = "change-me"
+
+// minSecretLen 签名密钥的最短长度，与 SHA-256 的输出长度一致
+const minSecretLen = 32
+
+// NewLocalStorage 创建本地存储，目录不存在时自动创建。签名地址的有效期不超过 maxExpiry，
+// 持有密钥以外的人无法伪造签名，因此拒绝空的、占位的或过短的密钥
+func NewLocalStorage(dir, urlPath string, secret []byte, maxExpiry time.Duration) (*LocalStorage, error) {
+	if dir == "" {
+		return nil, errors.New("local storage dir is empty")
+	}
+	if len(secret) == 0 {
+		return nil, errors.New("local storage secret is empty")
+	}
+	if string(secret) == placeholderSecret {
+		return nil, errors.New("local storage secret is still the placeholder, generate a random secret")
+	}
+	if len(secret) < minSecretLen {
+		return nil, fmt.Errorf("local storage secret must be at least %d bytes", minSecretLen)
+	}
+	if maxExpiry <= 0 {
+		return nil, errors.New("local storage max expiry must be positive")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &LocalStorage{dir: dir, urlPath: strings.TrimSuffix(urlPath, "/"), secret: secret, maxExpiry: maxExpiry}, nil
+}
+
+// path 把 key 转换为本地文件路径，拒绝跳出根目录的 key
+func (s *LocalStorage) path(key string) (string, error) {
+	cleaned := path.Clean("/" + key)[1:]
+	if cleaned == "" || cleaned != key {
+		return "", fmt.Errorf("invalid key %q", key)
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(cleaned)), nil
+}
+
+// Put 先写入临时文件再改名，避免读到写了一半的文件
+func (s *LocalStorage) Put(key string, body io.Reader, size int64, contentType string) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, body); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), p)
+}
+
+// Delete 删除本地文件
+func (s *LocalStorage) Delete(key string) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Open 打开本地文件，供下载路由使用
+func (s *LocalStorage) Open(key string) (*os.File, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+// SignedURL 生成带过期时间和签名的下载路径，有效期不超过 maxExpiry
+func (s *LocalStorage) SignedURL(key string, expiry time.Duration) (string, error) {
+	if _, err := s.path(key); err != nil {
+		return "", err
+	}
+	expiry = min(expiry, s.maxExpiry)
+	expires := strconv.FormatInt(time.Now().Add(expiry).Unix(), 10)
+	query := url.Values{"expires": {expires}, "signature": {s.sign(key, expires)}}
+	return s.urlPath + "/" + key + "?" + query.Encode(), nil
+}
+
+// Verify 校验下载地址的签名和过期时间。过期时间晚于 now+maxExpiry 的地址不是由 SignedURL 签发的，一律拒绝
+func (s *LocalStorage) Verify(key, expires, signature string) bool {
+	deadline, err := strconv.ParseInt(expires, 10, 64)
+	now := time.Now()
+	if err != nil || now.Unix() > deadline || deadline > now.Add(s.maxExpiry).Unix() {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(s.sign(key, expires)))
+}
+
+func (s *LocalStorage) sign(key, expires string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(key + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testSecret 满足最短长度要求的测试密钥
+var testSecret = []byte(strings.Repeat("s", minSecretLen))
+
+func newTestLocalStorage(t *testing.T) *LocalStorage {
+	t.Helper()
+	s, err := NewLocalStorage(t.TempDir(), "/media", testSecret, 15*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestLocalPathRejectsTraversal(t *testing.T) {
+	s := newTestLocalStorage(t)
+
+	for _, key := range []string{"", "/", "../secret", "a/../../b", "a/../b", "/etc/passwd", "a//b", "./a", "a/"} {
+		if p, err := s.path(key); err == nil {
+			t.Errorf("path(%q) = %q, want error", key, p)
+		}
+	}
+
+	p, err := s.path("images/2024/a.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(s.dir, "images", "2024", "a.png"); p != want {
+		t.Errorf("path = %q, want %q", p, want)
+	}
+}
+
+// signedParams 从签名地址中取出 key、expires 和 signature
+func signedParams(t *testing.T, s *LocalStorage, key string, expiry time.Duration) (string, string, string) {
+	t.Helper()
+	signed, err := s.SignedURL(key, expiry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.TrimPrefix(u.Path, "/media/"), u.Query().Get("expires"), u.Query().Get("signature")
+}
+
+func TestLocalVerify(t *testing.T) {
+	s := newTestLocalStorage(t)
+
+	key, expires, signature := signedParams(t, s, "images/a.png", time.Minute)
+	if key != "images/a.png" {
+		t.Fatalf("key = %q", key)
+	}
+	if !s.Verify(key, expires, signature) {
+		t.Fatal("valid signature rejected")
+	}
+
+	later := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+	tests := []struct {
+		name                    string
+		key, expires, signature string
+	}{
+		{"other key", "images/b.png", expires, signature},
+		{"extended expiry", key, later, signature},
+		{"tampered signature", key, expires, strings.Repeat("0", len(signature))},
+		{"empty signature", key, expires, ""},
+		{"invalid expiry", key, "soon", signature},
+	}
+	for _, tt := range tests {
+		if s.Verify(tt.key, tt.expires, tt.signature) {
+			t.Errorf("%s: Verify accepted a forged URL", tt.name)
+		}
+	}
+
+	other, err := NewLocalStorage(t.TempDir(), "/media", []byte(strings.Repeat("o", minSecretLen)), 15*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.Verify(key, expires, signature) {
+		t.Error("signature accepted with a different secret")
+	}
+}
+
+func TestLocalVerifyExpired(t *testing.T) {
+	s := newTestLocalStorage(t)
+
+	key, expires, signature := signedParams(t, s, "images/a.png", -time.Second)
+	if s.Verify(key, expires, signature) {
+		t.Error("expired URL accepted")
+	}
+}
+
+func TestLocalVerifyCapsExpiry(t *testing.T) {
+	s := newTestLocalStorage(t)
+
+	// 持有密钥也无法让地址的有效期超过上限
+	_, expires, _ := signedParams(t, s, "images/a.png", 365*24*time.Hour)
+	deadline, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limit := time.Now().Add(s.maxExpiry).Unix(); deadline > limit {
+		t.Errorf("expires = %d, want at most %d", deadline, limit)
+	}
+
+	forever := strconv.FormatInt(time.Now().Add(365*24*time.Hour).Unix(), 10)
+	if s.Verify("images/a.png", forever, s.sign("images/a.png", forever)) {
+		t.Error("URL beyond the maximum expiry accepted")
+	}
+}
+
+func TestNewLocalStorageRejectsWeakSecrets(t *testing.T) {
+	for _, secret := range []string{"", placeholderSecret, "short secret"} {
+		if _, err := NewLocalStorage(t.TempDir(), "/media", []byte(secret), time.Minute); err == nil {
+			t.Errorf("NewLocalStorage accepted secret %q", secret)
+		}
+	}
+	if _, err := NewLocalStorage(t.TempDir(), "/media", testSecret, 0); err == nil {
+		t.Error("NewLocalStorage accepted a zero max expiry")
+	}
+}
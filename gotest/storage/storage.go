@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"errors"
+	"exchangeapp/config"
+	"fmt"
+	"io"
+	"log"
+	"time"
+)
+
+// ErrNotFound 对象不存在
+var ErrNotFound = errors.New("object not found")
+
+// Storage 上传文件的存储后端，key 为不以 / 开头的相对路径
+type Storage interface {
+	// Put 写入对象，size 为内容的字节数
+	Put(key string, body io.Reader, size int64, contentType string) error
+	// Delete 删除对象，对象不存在时不报错
+	Delete(key string) error
+	// SignedURL 生成在 expiry 时长内有效的下载地址
+	SignedURL(key string, expiry time.Duration) (string, error)
+}
+
+// DefaultURLExpiry 配置中未设置时签名下载地址的有效期
+const DefaultURLExpiry = 15 * time.Minute
+
+// URLExpiry 签名下载地址的有效期，本地存储同时以此作为下载地址有效期的上限
+func URLExpiry() time.Duration {
+	if expiry := config.AppConfig.Storage.URLExpiry; expiry > 0 {
+		return expiry
+	}
+	return DefaultURLExpiry
+}
+
+// Default 全局使用的存储实现，由 InitStorage 根据配置初始化
+var Default Storage
+
+// InitStorage 根据配置选择存储实现：local（默认，本地文件系统）或 s3（S3 兼容的对象存储）
+func InitStorage() {
+	cfg := config.AppConfig.Storage
+	var err error
+
+	switch cfg.Driver {
+	case "", "local":
+		Default, err = NewLocalStorage(cfg.Local.Dir, "/media", []byte(cfg.Secret), URLExpiry())
+	case "s3":
+		Default, err = NewS3Storage(cfg.S3.Endpoint, cfg.S3.Region, cfg.S3.Bucket,
+			cfg.S3.AccessKeyID, cfg.S3.SecretAccessKey, cfg.S3.UsePathStyle)
+	default:
+		err = fmt.Errorf("unknown storage driver %q", cfg.Driver)
+	}
+
+	if err != nil {
+		log.Fatalf("文件存储初始化失败: %v", err)
+	}
+}
@@ -0,0 +1,216 @@
+package textdiff
+
+import (
+	"strings"
+	"unicode"
+)
+
+// 差异片段的类型
+const (
+	Equal  = "equal"
+	Insert = "insert"
+	Delete = "delete"
+)
+
+// Op 一段连续的相同、新增或删除的文本
+type Op struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// Lines 按行比较两段文本
+func Lines(a, b string) []Op {
+	return Diff(splitLines(a), splitLines(b))
+}
+
+// Words 按单词比较两段文本，空白作为独立的片段保留，中日韩文字逐字比较
+func Words(a, b string) []Op {
+	return Diff(splitWords(a), splitWords(b))
+}
+
+// 差异计算的上限。Myers 算法的时间为 O((N+M)·D)，回溯记录的内存为 O(D²)，
+// 超过上限时不再计算精细的差异，把不同的部分整体作为一次替换（删除旧文本、插入新文本）
+var (
+	MaxTokens = 100000 // 两段文本去掉相同的开头和结尾后，片段总数的上限
+	MaxEdits  = 2000   // 编辑步数的上限
+)
+
+// Diff 使用 Myers 算法计算把 a 变为 b 的最短编辑序列，相邻的同类片段会被合并。
+// 差异超过 MaxTokens 或 MaxEdits 时，不同的部分退化为一次整体替换
+func Diff(a, b []string) []Op {
+	// 相同的开头和结尾不参与计算
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var ops []Op
+	for _, t := range a[:prefix] {
+		ops = append(ops, Op{Type: Equal, Text: t})
+	}
+	middleA, middleB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	middle, ok := myers(middleA, middleB)
+	if !ok {
+		middle = nil
+		for _, t := range middleA {
+			middle = append(middle, Op{Type: Delete, Text: t})
+		}
+		for _, t := range middleB {
+			middle = append(middle, Op{Type: Insert, Text: t})
+		}
+	}
+	ops = append(ops, middle...)
+	for _, t := range a[len(a)-suffix:] {
+		ops = append(ops, Op{Type: Equal, Text: t})
+	}
+	return merge(ops)
+}
+
+// myers 计算最短编辑序列，超过 MaxTokens 或 MaxEdits 时返回 false
+func myers(a, b []string) ([]Op, bool) {
+	n, m := len(a), len(b)
+	if n+m > MaxTokens {
+		return nil, false
+	}
+	maxD := min(n+m, MaxEdits)
+	offset := maxD + 1
+
+	// v[k] 记录对角线 k 上能到达的最远 x；trace[d] 保存第 d 步开始时 v 在 [-d, d] 上的值，用于回溯
+	v := make([]int, 2*maxD+3)
+	var trace [][]int
+
+	found := false
+	for d := 0; d <= maxD && !found; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				found = true
+				break
+			}
+		}
+	}
+	if !found {
+		return nil, false
+	}
+
+	// 从终点沿 trace 回溯，逆序得到编辑序列
+	var ops []Op
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		vd := trace[d]
+		at := func(k int) int { return vd[k+d] }
+		k := x - y
+
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := 0
+		if d > 0 {
+			prevX = at(prevK)
+		}
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			ops = append(ops, Op{Type: Equal, Text: a[x]})
+		}
+		if d > 0 {
+			if x == prevX {
+				ops = append(ops, Op{Type: Insert, Text: b[prevY]})
+			} else {
+				ops = append(ops, Op{Type: Delete, Text: a[prevX]})
+			}
+		}
+		x, y = prevX, prevY
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops, true
+}
+
+// merge 合并相邻的同类片段
+func merge(ops []Op) []Op {
+	merged := []Op{}
+	for _, op := range ops {
+		if last := len(merged) - 1; last >= 0 && merged[last].Type == op.Type {
+			merged[last].Text += op.Text
+			continue
+		}
+		merged = append(merged, op)
+	}
+	return merged
+}
+
+// splitLines 按行切分，每行保留结尾的换行符
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	// 以换行结尾时 SplitAfter 会多出一个空串
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// splitWords 切分为单词、空白和标点，中日韩文字每个字单独成为一个片段
+func splitWords(text string) []string {
+	var tokens []string
+	start := -1
+	kind := 0
+	for i, r := range text {
+		k := tokenKind(r)
+		if start >= 0 && (k != kind || k == cjkToken || k == punctToken) {
+			tokens = append(tokens, text[start:i])
+			start = -1
+		}
+		if start < 0 {
+			start, kind = i, k
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, text[start:])
+	}
+	return tokens
+}
+
+// 切分单词时字符的类别
+const (
+	wordToken = iota + 1
+	spaceToken
+	cjkToken
+	punctToken
+)
+
+func tokenKind(r rune) int {
+	switch {
+	case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+		return cjkToken
+	case unicode.IsSpace(r):
+		return spaceToken
+	case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_':
+		return wordToken
+	default:
+		return punctToken
+	}
+}
@@ -0,0 +1,168 @@
+package textdiff
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// apply 由差异还原旧文本（相同加删除）和新文本（相同加新增）
+func apply(ops []Op) (string, string) {
+	var a, b strings.Builder
+	for _, op := range ops {
+		switch op.Type {
+		case Equal:
+			a.WriteString(op.Text)
+			b.WriteString(op.Text)
+		case Delete:
+			a.WriteString(op.Text)
+		case Insert:
+			b.WriteString(op.Text)
+		}
+	}
+	return a.String(), b.String()
+}
+
+// checkOps 校验差异能还原两段文本，且相邻片段的类型不同、片段不为空
+func checkOps(t *testing.T, name, a, b string, ops []Op) {
+	t.Helper()
+	if gotA, gotB := apply(ops); gotA != a || gotB != b {
+		t.Errorf("%s: round trip = (%q, %q), want (%q, %q)", name, gotA, gotB, a, b)
+	}
+	for i, op := range ops {
+		if op.Text == "" {
+			t.Errorf("%s: empty op at %d: %v", name, i, ops)
+		}
+		if i > 0 && ops[i-1].Type == op.Type {
+			t.Errorf("%s: adjacent %s ops not merged: %v", name, op.Type, ops)
+		}
+	}
+}
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want []Op
+	}{
+		{"both empty", "", "", []Op{}},
+		{"from empty", "", "a\nb\n", []Op{{Insert, "a\nb\n"}}},
+		{"to empty", "a\nb\n", "", []Op{{Delete, "a\nb\n"}}},
+		{"identical", "a\nb\n", "a\nb\n", []Op{{Equal, "a\nb\n"}}},
+		{"changed line", "a\nb\nc\n", "a\nx\nc\n", []Op{{Equal, "a\n"}, {Delete, "b\n"}, {Insert, "x\n"}, {Equal, "c\n"}}},
+		{"inserted line", "a\nc\n", "a\nb\nc\n", []Op{{Equal, "a\n"}, {Insert, "b\n"}, {Equal, "c\n"}}},
+		{"added trailing newline", "a\nb", "a\nb\n", []Op{{Equal, "a\n"}, {Delete, "b"}, {Insert, "b\n"}}},
+		{"removed trailing newline", "a\nb\n", "a\nb", []Op{{Equal, "a\n"}, {Delete, "b\n"}, {Insert, "b"}}},
+	}
+	for _, tt := range tests {
+		got := Lines(tt.a, tt.b)
+		checkOps(t, tt.name, tt.a, tt.b, got)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Lines = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWords(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want []Op
+	}{
+		{"identical", "hello world", "hello world", []Op{{Equal, "hello world"}}},
+		{"changed word", "the quick fox", "the slow fox", []Op{{Equal, "the "}, {Delete, "quick"}, {Insert, "slow"}, {Equal, " fox"}}},
+		{"punctuation", "hello, world", "hello! world", []Op{{Equal, "hello"}, {Delete, ","}, {Insert, "!"}, {Equal, " world"}}},
+		{"cjk per character", "我喜欢猫", "我喜欢狗", []Op{{Equal, "我喜欢"}, {Delete, "猫"}, {Insert, "狗"}}},
+		{"cjk inserted", "汇率上涨", "汇率大幅上涨", []Op{{Equal, "汇率"}, {Insert, "大幅"}, {Equal, "上涨"}}},
+		{"mixed scripts", "USD汇率", "EUR汇率", []Op{{Delete, "USD"}, {Insert, "EUR"}, {Equal, "汇率"}}},
+	}
+	for _, tt := range tests {
+		got := Words(tt.a, tt.b)
+		checkOps(t, tt.name, tt.a, tt.b, got)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Words = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSplitWords(t *testing.T) {
+	got := splitWords("Hi, 你好 world_1!")
+	want := []string{"Hi", ",", " ", "你", "好", " ", "world_1", "!"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("splitWords = %q, want %q", got, want)
+	}
+}
+
+// lcs 用动态规划计算最长公共子序列的长度，作为最短编辑序列的参照
+func lcs(a, b []string) int {
+	dp := make([][]int, len(a)+1)
+	for i := range dp {
+		dp[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				dp[i][j] = dp[i+1][j+1] + 1
+			} else {
+				dp[i][j] = max(dp[i+1][j], dp[i][j+1])
+			}
+		}
+	}
+	return dp[0][0]
+}
+
+func TestDiffIsMinimal(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	alphabet := []string{"a", "b", "c", "d"}
+	randomTokens := func() []string {
+		tokens := make([]string, r.Intn(12))
+		for i := range tokens {
+			tokens[i] = alphabet[r.Intn(len(alphabet))]
+		}
+		return tokens
+	}
+
+	for i := 0; i < 500; i++ {
+		a, b := randomTokens(), randomTokens()
+		ops := Diff(a, b)
+		checkOps(t, "random", strings.Join(a, ""), strings.Join(b, ""), ops)
+
+		equal := 0
+		for _, op := range ops {
+			if op.Type == Equal {
+				equal += len(op.Text) // 每个片段只有一个字符
+			}
+		}
+		if want := lcs(a, b); equal != want {
+			t.Fatalf("Diff(%q, %q) keeps %d tokens, want %d: %v", a, b, equal, want, ops)
+		}
+	}
+}
+
+func TestDiffFallback(t *testing.T) {
+	defer func(tokens, edits int) { MaxTokens, MaxEdits = tokens, edits }(MaxTokens, MaxEdits)
+
+	a, b := "same a b tail", "same c d tail"
+	// 精确差异保留中间的空格；超过上限时中间整体替换，相同的开头和结尾仍保留
+	exact := []Op{{Equal, "same "}, {Delete, "a"}, {Insert, "c"}, {Equal, " "}, {Delete, "b"}, {Insert, "d"}, {Equal, " tail"}}
+	coarse := []Op{{Equal, "same "}, {Delete, "a b"}, {Insert, "c d"}, {Equal, " tail"}}
+
+	tests := []struct {
+		name                string
+		maxTokens, maxEdits int
+		want                []Op
+	}{
+		{"within limits", 100, 100, exact},
+		{"too many edits", 100, 3, coarse},
+		{"too many tokens", 5, 100, coarse},
+	}
+	for _, tt := range tests {
+		MaxTokens, MaxEdits = tt.maxTokens, tt.maxEdits
+		got := Words(a, b)
+		checkOps(t, tt.name, a, b, got)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Words = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"errors"
+	"exchangeapp/models/user"
+	"strings"
+)
+
+// 登录标识的类型
+const (
+	IdentifierEmail    = "email"
+	IdentifierPhone    = "phone"
+	IdentifierUsername = "username"
+)
+
+// ErrInvalidIdentifier 登录标识看起来是邮箱或手机号，但格式无效
+var ErrInvalidIdentifier = errors.New("invalid identifier")
+
+// NormalizeEmail 邮箱统一转为小写，存储和查询都使用规范化后的邮箱
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// NormalizePhone 把手机号规范化为 E.164 格式（如 +8613800138000），规则见 user.NormalizePhone
+func NormalizePhone(phone string) (string, error) {
+	normalized, err := user.NormalizePhone(phone)
+	if err != nil {
+		return "", ErrInvalidIdentifier
+	}
+	return normalized, nil
+}
+
+// ParseIdentifier 判断登录标识是邮箱、手机号还是用户名，并返回规范化后的值。
+// 含 @ 的视为邮箱；由数字和分隔符组成的视为手机号；其余视为用户名，用户名原样返回
+func ParseIdentifier(identifier string) (kind string, value string, err error) {
+	identifier = strings.TrimSpace(identifier)
+	if strings.Contains(identifier, "@") {
+		email := NormalizeEmail(identifier)
+		if !IsValidEmail(email) {
+			return IdentifierEmail, "", ErrInvalidIdentifier
+		}
+		return IdentifierEmail, email, nil
+	}
+	if user.IsPhoneLike(identifier) {
+		phone, err := NormalizePhone(identifier)
+		return IdentifierPhone, phone, err
+	}
+	return IdentifierUsername, identifier, nil
+}
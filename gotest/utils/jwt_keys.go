@@ -0,0 +1,163 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"exchangeapp/config"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// signingKey 一个 JWT 签名密钥。签发只使用当前密钥，验证时按令牌头部的 kid 查找密钥，
+// 因此轮换密钥后，旧密钥签发的令牌在过期前仍然有效，用户不会被登出
+type signingKey struct {
+	method jwt.SigningMethod
+	sign   interface{} // 签名用的密钥，只用于验证的旧密钥为 nil
+	verify interface{} // 验证用的密钥
+}
+
+var (
+	signingKeys   = make(map[string]signingKey)
+	activeKeyID   string
+	tokenIssuer   string
+	tokenAudience string
+)
+
+// InitJWT 从配置中加载 JWT 的签名密钥、签发者、受众和有效期
+func InitJWT() {
+	if err := loadJWTConfig(); err != nil {
+		log.Fatalf("JWT 初始化失败: %v", err)
+	}
+}
+
+func loadJWTConfig() error {
+	cfg := config.AppConfig.JWT
+
+	keys := make(map[string]signingKey, len(cfg.Keys))
+	for _, k := range cfg.Keys {
+		if k.ID == "" {
+			return errors.New("jwt key without id")
+		}
+		if _, ok := keys[k.ID]; ok {
+			return fmt.Errorf("duplicate jwt key %q", k.ID)
+		}
+		algorithm := k.Algorithm
+		if algorithm == "" {
+			algorithm = cfg.Algorithm
+		}
+		key, err := loadSigningKey(algorithm, k.Secret, k.PrivateKeyFile, k.PublicKeyFile)
+		if err != nil {
+			return fmt.Errorf("jwt key %q: %w", k.ID, err)
+		}
+		keys[k.ID] = key
+	}
+
+	active, ok := keys[cfg.ActiveKey]
+	if !ok {
+		return fmt.Errorf("active jwt key %q is not configured", cfg.ActiveKey)
+	}
+	if active.sign == nil {
+		return fmt.Errorf("active jwt key %q has no private key", cfg.ActiveKey)
+	}
+
+	signingKeys, activeKeyID = keys, cfg.ActiveKey
+	tokenIssuer, tokenAudience = cfg.Issuer, cfg.Audience
+	if cfg.AccessTTL > 0 {
+		AccessTokenTTL = cfg.AccessTTL
+	}
+	if cfg.RefreshTTL > 0 {
+		RefreshTokenTTL = cfg.RefreshTTL
+	}
+	return nil
+}
+
+// placeholderSecret 示例配置中的占位密钥，不允许直接使用
+const placeholderSecret = "change-me"
+
+// minHS256SecretLen HS256 密钥的最短长度，与 SHA-256 的输出长度一致
+const minHS256SecretLen = 32
+
+// loadSigningKey 按算法加载密钥。RS256 和 EdDSA 有私钥时由私钥推出公钥，只有公钥时只能用于验证
+func loadSigningKey(algorithm, secret, privateKeyFile, publicKeyFile string) (signingKey, error) {
+	var key signingKey
+	switch algorithm {
+	case "", "HS256":
+		if secret == "" {
+			return key, errors.New("HS256 requires a secret")
+		}
+		if secret == placeholderSecret {
+			return key, errors.New("HS256 secret is still the placeholder, generate a random secret")
+		}
+		if len(secret) < minHS256SecretLen {
+			return key, fmt.Errorf("HS256 secret must be at least %d bytes", minHS256SecretLen)
+		}
+		key = signingKey{method: jwt.SigningMethodHS256, sign: []byte(secret), verify: []byte(secret)}
+	case "RS256":
+		key.method = jwt.SigningMethodRS256
+		if privateKeyFile != "" {
+			data, err := os.ReadFile(privateKeyFile)
+			if err != nil {
+				return key, err
+			}
+			private, err := jwt.ParseRSAPrivateKeyFromPEM(data)
+			if err != nil {
+				return key, err
+			}
+			key.sign, key.verify = private, &private.PublicKey
+		}
+		if publicKeyFile != "" {
+			data, err := os.ReadFile(publicKeyFile)
+			if err != nil {
+				return key, err
+			}
+			if key.verify, err = jwt.ParseRSAPublicKeyFromPEM(data); err != nil {
+				return key, err
+			}
+		}
+	case "EdDSA":
+		key.method = jwt.SigningMethodEdDSA
+		if privateKeyFile != "" {
+			data, err := os.ReadFile(privateKeyFile)
+			if err != nil {
+				return key, err
+			}
+			private, err := jwt.ParseEdPrivateKeyFromPEM(data)
+			if err != nil {
+				return key, err
+			}
+			key.sign, key.verify = private, private.(ed25519.PrivateKey).Public()
+		}
+		if publicKeyFile != "" {
+			data, err := os.ReadFile(publicKeyFile)
+			if err != nil {
+				return key, err
+			}
+			if key.verify, err = jwt.ParseEdPublicKeyFromPEM(data); err != nil {
+				return key, err
+			}
+		}
+	default:
+		return key, fmt.Errorf("unsupported algorithm %q", algorithm)
+	}
+
+	if key.verify == nil {
+		return key, fmt.Errorf("%s requires a private or public key file", algorithm)
+	}
+	return key, nil
+}
+
+// verificationKey 按令牌头部的 kid 查找验证密钥，并要求令牌的算法与密钥一致，防止算法混淆攻击
+func verificationKey(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := signingKeys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, errors.New("unexpected Signing Method")
+	}
+	return key.verify, nil
+}
@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"exchangeapp/global"
+	"strings"
+	"time"
+)
+
+// 访问令牌和刷新令牌的有效期，可通过配置 jwt.accessTTL 和 jwt.refreshTTL 修改。
+// 访问令牌有效期短，过期后用刷新令牌换取新的令牌
+var (
+	AccessTokenTTL  = 15 * time.Minute
+	RefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// AccessClaims 访问令牌中的声明
+type AccessClaims struct {
+	UserID    uint
+	Username  string
+	Level     int      // 签发时的账号等级
+	Roles     []string // 签发时的系统角色
+	JTI       string   // 令牌ID，吊销时加入黑名单
+	ExpiresAt time.Time
+}
+
+// randomHex 生成 n 字节的随机数，以十六进制字符串返回
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// GenerateRefreshToken 生成不透明的随机刷新令牌。服务端只保存其哈希，数据库泄露也无法冒用
+func GenerateRefreshToken() (token string, hash string, err error) {
+	token, err = randomHex(32)
+	if err != nil {
+		return "", "", err
+	}
+	return token, HashRefreshToken(token), nil
+}
+
+// HashRefreshToken 计算刷新令牌的哈希，用于存储和查找
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewTokenFamily 生成新的令牌族ID。同一次登录轮换出的刷新令牌属于同一族，检测到重复使用时整族吊销
+func NewTokenFamily() (string, error) {
+	return randomHex(16)
+}
+
+// StripBearer 去掉 Authorization 中可选的 "Bearer " 前缀，兼容直接传入令牌的旧客户端
+func StripBearer(header string) string {
+	header = strings.TrimSpace(header)
+	if len(header) > 7 && strings.EqualFold(header[:7], "Bearer ") {
+		return strings.TrimSpace(header[7:])
+	}
+	return header
+}
+
+func denylistKey(jti string) string {
+	return "auth:denylist:" + jti
+}
+
+// RevokeAccessToken 把访问令牌加入黑名单直到其过期，已过期的令牌无需处理
+func RevokeAccessToken(jti string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+	return global.RedisDB.Set(denylistKey(jti), 1, ttl).Err()
+}
+
+// IsAccessTokenRevoked 判断访问令牌是否已被吊销
+func IsAccessTokenRevoked(jti string) (bool, error) {
+	n, err := global.RedisDB.Exists(denylistKey(jti)).Result()
+	return n > 0, err
+}
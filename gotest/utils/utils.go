@@ -2,7 +2,7 @@ package utils
 
 import (
 	"errors"
-	"exchangeapp/global"
+	"exchangeapp/models/user"
 	"time"
 
 	"github.com/golang-jwt/jwt"
@@ -17,18 +17,45 @@ func HashPassword(pwd string) (string, error) {
 	return string(hash), err
 }
 
-// GenerateJWT 用于生成一个带有用户名和过期时间（72小时）的 JWT
-func GenerateJWT(username string) (string, error) {
-	// 创建一个新的 JWT，使用 HS256 算法，payload 包含用户名和过期时间
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"username": username,                              // 账号
-		"exp":      time.Now().Add(time.Hour * 72).Unix(), // 设置 JWT 过期时间为当前时间的 72 小时后
-	})
+// GenerateJWT 用当前密钥生成访问令牌，有效期为 AccessTokenTTL，并带有唯一的 jti 以便随时吊销
+func GenerateJWT(u user.User) (string, AccessClaims, error) {
+	jti, err := randomHex(16)
+	if err != nil {
+		return "", AccessClaims{}, err
+	}
+	claims := AccessClaims{
+		UserID:    u.ID,
+		Username:  u.Username,
+		Level:     u.Level,
+		Roles:     u.Roles(),
+		JTI:       jti,
+		ExpiresAt: time.Now().Add(AccessTokenTTL),
+	}
 
-	// 使用 secret 密钥对 token 进行签名，返回签名后的 JWT 字符串
-	signedToken, err := token.SignedString([]byte(global.JwtSecret))
-	// 返回带有 "Bearer " 前缀的 JWT 和可能发生的错误
-	return signedToken, err
+	// payload 包含用户ID、用户名、等级、角色、jti 和有效期，处理请求时无需再查询用户
+	mapClaims := jwt.MapClaims{
+		"uid":      claims.UserID,           // 用户ID
+		"username": claims.Username,         // 账号
+		"level":    claims.Level,            // 账号等级
+		"roles":    claims.Roles,            // 系统角色
+		"jti":      jti,                     // 令牌ID，用于吊销
+		"iat":      time.Now().Unix(),       // 签发时间
+		"exp":      claims.ExpiresAt.Unix(), // 过期时间
+	}
+	if tokenIssuer != "" {
+		mapClaims["iss"] = tokenIssuer
+	}
+	if tokenAudience != "" {
+		mapClaims["aud"] = tokenAudience
+	}
+
+	// 使用当前密钥签名，并在头部写入 kid，验证时据此选择密钥
+	key := signingKeys[activeKeyID]
+	token := jwt.NewWithClaims(key.method, mapClaims)
+	token.Header["kid"] = activeKeyID
+
+	signedToken, err := token.SignedString(key.sign)
+	return signedToken, claims, err
 }
 
 // CheckPassword 用于验证输入的密码与存储的哈希密码是否匹配
@@ -39,40 +66,62 @@ func CheckPassword(password string, hash string) bool {
 	return err == nil
 }
 
-// ParseJWT 用于解析 JWT，并从中提取用户名
-func ParseJWT(tokenString string) (string, error) {
-	// 检查 token 字符串是否以 "Bearer " 开头，若是则去掉 "Bearer " 前缀
-	//if len(tokenString) > 7 && tokenString[:7] == "Bearer " {
-	//	tokenString = tokenString[7:]
-	//}
-
-	// 解析 JWT，并验证签名方法是否是 HMAC（HS256）
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		// 检查签名方法是否是 HMAC
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected Signing Method") // 如果签名方法不正确，返回错误
-		}
-		// 返回用于签名的密钥（这里是 "secret"）
-		return []byte(global.JwtSecret), nil
-	})
+// ParseJWT 用于解析访问令牌，验证签名、签发者和受众，并提取其中的声明；不检查令牌是否已被吊销
+func ParseJWT(tokenString string) (AccessClaims, error) {
+	// 按头部的 kid 选择验证密钥
+	token, err := jwt.Parse(tokenString, verificationKey)
 
 	// 如果解析失败，返回错误
 	if err != nil {
-		return "", err
+		return AccessClaims{}, err
 	}
 
 	// 如果 JWT 有效，提取其中的 claims（负载数据）
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		// 获取用户名
-		username, ok := claims["username"].(string)
-		// 如果用户名不是字符串类型，返回错误
-		if !ok {
-			return "", errors.New("username claim is not a string")
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return AccessClaims{}, errors.New("invalid token")
+	}
+	if tokenIssuer != "" && !claims.VerifyIssuer(tokenIssuer, true) {
+		return AccessClaims{}, errors.New("invalid issuer")
+	}
+	if tokenAudience != "" && !claims.VerifyAudience(tokenAudience, true) {
+		return AccessClaims{}, errors.New("invalid audience")
+	}
+
+	username, ok := claims["username"].(string)
+	if !ok {
+		return AccessClaims{}, errors.New("username claim is not a string")
+	}
+	// 没有用户ID的旧令牌不再接受，客户端用刷新令牌换取新令牌即可
+	uid, ok := claims["uid"].(float64)
+	if !ok || uid <= 0 {
+		return AccessClaims{}, errors.New("token has no user id")
+	}
+	level, _ := claims["level"].(float64)
+	var roles []string
+	if list, ok := claims["roles"].([]interface{}); ok {
+		for _, r := range list {
+			if role, ok := r.(string); ok {
+				roles = append(roles, role)
+			}
 		}
-		// 返回用户名
-		return username, nil
+	}
+	// 没有 jti 的旧令牌无法吊销，不再接受
+	jti, ok := claims["jti"].(string)
+	if !ok || jti == "" {
+		return AccessClaims{}, errors.New("token has no jti")
+	}
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return AccessClaims{}, errors.New("token has no expiry")
 	}
 
-	// 如果 JWT 无效，返回错误
-	return "", err
+	return AccessClaims{
+		UserID:    uint(uid),
+		Username:  username,
+		Level:     int(level),
+		Roles:     roles,
+		JTI:       jti,
+		ExpiresAt: time.Unix(int64(exp), 0),
+	}, nil
 }
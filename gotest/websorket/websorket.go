@@ -2,8 +2,6 @@ package websorket
 
 import (
 	"encoding/json"
-	"exchangeapp/global"
-	"exchangeapp/models/user"
 	"exchangeapp/utils"
 	"fmt"
 	"github.com/gorilla/websocket"
@@ -27,12 +25,40 @@ var upgrader = websocket.Upgrader{
 	},
 }
 
-var clients = make(map[int]*websocket.Conn) // 存储用户ID与WebSocket连接
+var clients = make(map[int]*client)         // 存储用户ID与WebSocket连接
 var lastHeartbeat = make(map[int]time.Time) // 存储每个用户的最后心跳时间
 var clientsMutex sync.Mutex
 
 var heartbeatTimeout = 5 * time.Second // 如果超时超过60秒认为用户掉线
 
+// client 一个在线用户的连接。gorilla/websocket 不允许并发写同一个连接，写入由 writeMu 串行化
+type client struct {
+	conn    *websocket.Conn
+	writeMu sync.Mutex
+}
+
+// writeTimeout 单次写入的超时时间，防止慢连接长时间阻塞推送
+const writeTimeout = 5 * time.Second
+
+// writeJSON 串行写入一条消息，每次写入前设置新的超时时间
+func (c *client) writeJSON(v interface{}) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if err := c.conn.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil {
+		return err
+	}
+	return c.conn.WriteJSON(v)
+}
+
+// lookupClient 在 clientsMutex 保护下查找用户的连接，写入在锁外进行，避免慢连接阻塞其他用户
+func lookupClient(userID int) (*client, bool) {
+	clientsMutex.Lock()
+	defer clientsMutex.Unlock()
+	c, online := clients[userID]
+	return c, online
+}
+
 // WebSocket连接处理
 func handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	// 升级HTTP请求到WebSocket连接
@@ -70,7 +96,7 @@ func handleWebSocket(w http.ResponseWriter, r *http.Request) {
 				}
 				// 将用户连接加入连接池
 				clientsMutex.Lock()
-				clients[userID] = conn
+				clients[userID] = &client{conn: conn}
 				lastHeartbeat[userID] = time.Now() // 记录首次心跳时间
 				clientsMutex.Unlock()
 				log.Printf("用户 %d 认证成功", userID)
@@ -88,7 +114,10 @@ func handleWebSocket(w http.ResponseWriter, r *http.Request) {
 			// 锁定并删除该用户的连接
 			clientsMutex.Lock()
 			log.Printf("User %d is offline", userID)
-			delete(clients, userID)
+			// 用户可能已用新连接重新认证，只删除本连接
+			if c, ok := clients[userID]; ok && c.conn == conn {
+				delete(clients, userID)
+			}
 			clientsMutex.Unlock() // 解锁
 
 			break
@@ -123,13 +152,11 @@ func handleWebSocket(w http.ResponseWriter, r *http.Request) {
 // 处理邀请逻辑
 func handleInvitation(invitation PendingInvitation) {
 	// 检查被邀请者是否在线
-	clientsMutex.Lock()
-	inviteeConn, online := clients[invitation.InviteeID]
-	clientsMutex.Unlock()
+	invitee, online := lookupClient(invitation.InviteeID)
 
 	if online {
 		// 被邀请者在线，发送邀请消息
-		err := inviteeConn.WriteJSON(invitation)
+		err := invitee.writeJSON(invitation)
 		if err != nil {
 			log.Println("Error sending invitation:", err)
 		} else {
@@ -149,22 +176,18 @@ func storePendingInvitation(invitation PendingInvitation) {
 // 获取用户ID
 func getUserID(token string) (int, error) {
 
-	// 解析 Token 获取用户名
-	username, err := utils.ParseJWT(token)
+	// 解析 Token 获取用户ID
+	claims, err := utils.ParseJWT(utils.StripBearer(token))
 	if err != nil {
 		log.Printf("Failed to parse token: %v", err)
 		return 0, fmt.Errorf("failed to parse token: %v", err)
 	}
-
-	// 根据 username 查询用户
-	var user user.User
-	if err := global.Db.Where("username = ?", username).First(&user).Error; err != nil {
-		log.Printf("User not found: %v", err)
-		return 0, fmt.Errorf("user not found")
+	if revoked, err := utils.IsAccessTokenRevoked(claims.JTI); err != nil || revoked {
+		return 0, fmt.Errorf("token has been revoked")
 	}
 
 	// 返回用户 ID
-	return int(user.ID), nil
+	return int(claims.UserID), nil
 }
 
 // 每隔一定时间检查所有用户的在线状态
@@ -174,14 +197,14 @@ func checkHeartbeat() {
 		select {
 		case <-ticker.C:
 			clientsMutex.Lock()
-			for userID, conn := range clients {
+			for userID, c := range clients {
 				// 检查每个用户的最后心跳时间
 				if time.Since(lastHeartbeat[userID]) > heartbeatTimeout {
 					// 如果超过超时，认为该用户掉线
 					log.Printf("User %d is offline", userID)
 					// 可以选择从连接池中移除，或者将其状态更新为离线
 					delete(clients, userID)
-					conn.Close()
+					c.conn.Close()
 				}
 			}
 			clientsMutex.Unlock()
@@ -204,3 +227,16 @@ func Initwebsorket() error {
 	}
 	return nil
 }
+
+// SendToUser 向在线用户推送一条消息，用户不在线或发送失败时返回 false
+func SendToUser(userID uint, msg interface{}) bool {
+	c, online := lookupClient(int(userID))
+	if !online {
+		return false
+	}
+	if err := c.writeJSON(msg); err != nil {
+		log.Printf("向用户 %d 推送消息失败: %v", userID, err)
+		return false
+	}
+	return true
+}